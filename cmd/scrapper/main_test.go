@@ -0,0 +1,31 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/eseis"
+	"github.com/idkw/eseisscrapper/pkg/usecases/scrapper"
+	"net/http"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, 0},
+		{"incomplete export", fmt.Errorf("%w: 2 items failed", scrapper.ErrIncompleteExport), 1},
+		{"invalid credentials", fmt.Errorf("failed to authenticate: %w", eseis.ErrInvalidCredentials), 1},
+		{"unauthorized", &eseis.APIError{StatusCode: http.StatusUnauthorized, URL: "http://eseis/v1/contracts"}, 1},
+		{"other error", errors.New("disk full"), 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := exitCode(test.err); got != test.want {
+				t.Errorf("got exit code %d, want %d", got, test.want)
+			}
+		})
+	}
+}
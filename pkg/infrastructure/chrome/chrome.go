@@ -11,23 +11,67 @@ type Chrome struct {
 	close func()
 }
 
-func NewChrome() *Chrome {
-	ctx, cancel := chromedp.NewContext(context.Background(), chromedp.WithLogf(logrus.Infof))
-	return &Chrome{
-		ctx: &ctx,
-		close: func() {
-			cancel()
-		},
+// NewChrome starts or connects to the browser described by config and opens a tab.
+// ctx only bounds the start, the browser stays open until Close.
+func NewChrome(ctx context.Context, config Config) (*Chrome, error) {
+	allocatorCtx, cancelAllocator := config.newAllocator(context.Background())
+	browserCtx, cancel := chromedp.NewContext(allocatorCtx, chromedp.WithLogf(logrus.Infof))
+	closeBrowser := func() {
+		cancel()
+		cancelAllocator()
 	}
+	// allocate the browser and its tab on the long-lived context so that
+	// cancelling the context of a single RunTasks call does not close them
+	started := make(chan error, 1)
+	go func() {
+		started <- chromedp.Run(browserCtx)
+	}()
+	select {
+	case err := <-started:
+		if err != nil {
+			closeBrowser()
+			return nil, err
+		}
+	case <-ctx.Done():
+		// cancelling the long-lived context aborts the start, e.g. a remote browser which never answers
+		closeBrowser()
+		<-started
+		return nil, ctx.Err()
+	}
+	return &Chrome{
+		ctx:   &browserCtx,
+		close: closeBrowser,
+	}, nil
 }
 
-func (c *Chrome) RunTasks(tasks chromedp.Tasks) error {
-	if err := chromedp.Run(*c.ctx, tasks); err != nil {
+// RunTasks runs the given tasks in the browser tab, aborting them as soon as ctx is done.
+// Cancelling ctx only interrupts the running tasks, the browser tab stays usable afterwards.
+func (c *Chrome) RunTasks(ctx context.Context, tasks chromedp.Tasks) error {
+	runCtx, cancel := context.WithCancel(*c.ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-runCtx.Done():
+		}
+	}()
+
+	if err := chromedp.Run(runCtx, tasks); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		return err
 	}
 	return nil
 }
 
+// ListenTarget calls fn with every event of the browser tab until the browser is closed.
+// fn is called synchronously and must not block.
+func (c *Chrome) ListenTarget(fn func(ev interface{})) {
+	chromedp.ListenTarget(*c.ctx, fn)
+}
+
 func (c *Chrome) Close() {
 	c.close()
 }
@@ -0,0 +1,60 @@
+package chrome
+
+import (
+	"context"
+	"github.com/chromedp/chromedp"
+	"strings"
+)
+
+// Config configures the browser used by Chrome
+type Config struct {
+	// RemoteURL is the devtools websocket url of an already running browser, e.g. ws://headless-shell:9222.
+	// When set no browser is started and the other options are ignored.
+	RemoteURL string `env:"ESEIS_CHROME_REMOTE_URL"`
+	// ExecPath is the path of the chrome binary, looked up in the PATH when empty
+	ExecPath string `env:"ESEIS_CHROME_EXEC_PATH"`
+	// Headful shows the browser window instead of running chrome headless
+	Headful bool `env:"ESEIS_CHROME_HEADFUL"`
+	// UserDataDir is the chrome profile directory, a temporary one is used when empty
+	UserDataDir  string `env:"ESEIS_CHROME_USER_DATA_DIR"`
+	WindowWidth  int    `env:"ESEIS_CHROME_WINDOW_WIDTH"`
+	WindowHeight int    `env:"ESEIS_CHROME_WINDOW_HEIGHT"`
+	// Flags are additional command line flags as name or name=value, without the leading dashes
+	Flags []string `env:"ESEIS_CHROME_FLAGS" envSeparator:","`
+}
+
+// newAllocator creates the allocator context connecting to or starting the configured browser
+func (c Config) newAllocator(parent context.Context) (context.Context, context.CancelFunc) {
+	if c.RemoteURL != "" {
+		return chromedp.NewRemoteAllocator(parent, c.RemoteURL)
+	}
+	return chromedp.NewExecAllocator(parent, c.execAllocatorOptions()...)
+}
+
+func (c Config) execAllocatorOptions() []chromedp.ExecAllocatorOption {
+	opts := append([]chromedp.ExecAllocatorOption{}, chromedp.DefaultExecAllocatorOptions[:]...)
+	if c.ExecPath != "" {
+		opts = append(opts, chromedp.ExecPath(c.ExecPath))
+	}
+	if c.Headful {
+		opts = append(opts, chromedp.Flag("headless", false), chromedp.Flag("hide-scrollbars", false), chromedp.Flag("mute-audio", false))
+	}
+	if c.UserDataDir != "" {
+		opts = append(opts, chromedp.UserDataDir(c.UserDataDir))
+	}
+	if c.WindowWidth > 0 && c.WindowHeight > 0 {
+		opts = append(opts, chromedp.WindowSize(c.WindowWidth, c.WindowHeight))
+	}
+	for _, flag := range c.Flags {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(strings.TrimSpace(flag), "-"), "=")
+		if name == "" {
+			continue
+		}
+		if hasValue {
+			opts = append(opts, chromedp.Flag(name, value))
+		} else {
+			opts = append(opts, chromedp.Flag(name, true))
+		}
+	}
+	return opts
+}
@@ -0,0 +1,129 @@
+package chrome
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeChrome writes a script which records its command line arguments, one per line, in the returned file and exits
+// without starting a browser
+func fakeChrome(t *testing.T) (execPath string, argsPath string) {
+	dir := t.TempDir()
+	argsPath = filepath.Join(dir, "args")
+	execPath = filepath.Join(dir, "chrome")
+	script := "#!/bin/sh\nprintf '%s\\n' \"$@\" > '" + argsPath + "'\nexit 1\n"
+	if err := os.WriteFile(execPath, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+	return execPath, argsPath
+}
+
+func startChrome(t *testing.T, config Config) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if chrome, err := NewChrome(ctx, config); err == nil {
+		chrome.Close()
+		t.Fatal("got a browser, want the start to fail")
+	}
+}
+
+func TestExecAllocatorOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		want    []string
+		notWant []string
+	}{
+		{
+			"defaults",
+			Config{},
+			[]string{"--headless", "--hide-scrollbars", "--mute-audio"},
+			[]string{"--window-size"},
+		},
+		{
+			"headful",
+			Config{Headful: true},
+			nil,
+			[]string{"--headless", "--hide-scrollbars", "--mute-audio"},
+		},
+		{
+			"user data dir and window size",
+			Config{UserDataDir: "/tmp/eseis-profile", WindowWidth: 1280, WindowHeight: 800},
+			[]string{"--user-data-dir=/tmp/eseis-profile", "--window-size=1280,800"},
+			nil,
+		},
+		{
+			"incomplete window size",
+			Config{WindowWidth: 1280},
+			nil,
+			[]string{"--window-size"},
+		},
+		{
+			"flags",
+			Config{Flags: []string{"--lang=fr-FR", " no-sandbox", "", "-", "proxy-server=http://proxy:3128"}},
+			[]string{"--lang=fr-FR", "--no-sandbox", "--proxy-server=http://proxy:3128"},
+			[]string{"--="},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			execPath, argsPath := fakeChrome(t)
+			test.config.ExecPath = execPath
+			startChrome(t, test.config)
+
+			content, err := os.ReadFile(argsPath)
+			if err != nil {
+				t.Fatalf("chrome was not started: %v", err)
+			}
+			args := strings.Split(strings.TrimSpace(string(content)), "\n")
+			for _, want := range test.want {
+				if !hasArg(args, want) {
+					t.Errorf("got args %v, want %s", args, want)
+				}
+			}
+			for _, notWant := range test.notWant {
+				if hasArg(args, notWant) {
+					t.Errorf("got args %v, want no %s", args, notWant)
+				}
+			}
+		})
+	}
+}
+
+// hasArg tells whether args contains arg or a flag starting with arg=
+func hasArg(args []string, arg string) bool {
+	for _, a := range args {
+		if a == arg || strings.HasPrefix(a, arg+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+func TestNewChromeConnectsToTheRemoteBrowser(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	execPath, argsPath := fakeChrome(t)
+	startChrome(t, Config{
+		RemoteURL: "ws" + strings.TrimPrefix(server.URL, "http"),
+		ExecPath:  execPath,
+	})
+
+	if len(requests) != 1 || requests[0] != "/json/version" {
+		t.Errorf("got requests %v to the remote browser, want the devtools url lookup", requests)
+	}
+	if _, err := os.Stat(argsPath); err == nil {
+		t.Error("chrome was started next to the remote browser")
+	}
+}
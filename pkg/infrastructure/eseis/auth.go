@@ -2,12 +2,18 @@ package eseis
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/sirupsen/logrus"
 	"net/http"
+	"strings"
 	"time"
 )
 
+const oauthTokenPath = "/v1/oauth/token"
+
 // authRequest is the authentication request payload for Eseis API
 type authRequest struct {
 	Username  string `json:"username"`
@@ -17,6 +23,13 @@ type authRequest struct {
 	Scope     string `json:"scope"`
 }
 
+// refreshRequest is the token refresh request payload for Eseis API
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+	ClientID     string `json:"client_id"`
+	GrantType    string `json:"grant_type"`
+}
+
 // authResponse is the authentication response payload from Eseis API
 type authResponse struct {
 	AccessToken  string `json:"access_token"`
@@ -33,7 +46,31 @@ type authToken struct {
 	refreshToken string
 }
 
-func (e *EseisClient) Authenticate() (*authToken, error) {
+// ErrInvalidCredentials matches login errors where the web UI or the oauth password grant of the API rejected
+// the client id, the username or the password
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// credentialsError is returned when the oauth password grant rejects the credentials,
+// errors.Is matches both ErrInvalidCredentials and the *APIError of the token request
+type credentialsError struct {
+	err error
+}
+
+func (e *credentialsError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrInvalidCredentials, e.err)
+}
+
+func (e *credentialsError) Is(target error) bool {
+	return target == ErrInvalidCredentials
+}
+
+func (e *credentialsError) Unwrap() error {
+	return e.err
+}
+
+// Authenticate logs in with the configured username and password using the oauth password grant.
+// The returned error matches ErrInvalidCredentials if the API rejected the credentials.
+func (e *EseisClient) Authenticate(ctx context.Context) (*authToken, error) {
 	requestBody := authRequest{
 		Username:  e.config.Username,
 		Password:  e.config.Password,
@@ -41,29 +78,44 @@ func (e *EseisClient) Authenticate() (*authToken, error) {
 		GrantType: "password",
 		Scope:     "eseis",
 	}
+	token, err := e.requestToken(ctx, requestBody)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusBadRequest || apiErr.StatusCode == http.StatusUnauthorized) {
+		// the oauth server answers invalid_grant or invalid_client
+		return nil, &credentialsError{err: err}
+	}
+	return token, err
+}
+
+// refreshAuthentication exchanges the refresh token for a new token using the oauth refresh_token grant
+func (e *EseisClient) refreshAuthentication(ctx context.Context, refreshToken string) (*authToken, error) {
+	requestBody := refreshRequest{
+		RefreshToken: refreshToken,
+		ClientID:     e.config.ClientId,
+		GrantType:    "refresh_token",
+	}
+	return e.requestToken(ctx, requestBody)
+}
+
+func (e *EseisClient) requestToken(ctx context.Context, requestBody any) (*authToken, error) {
 	requestBodyBytes, err := json.Marshal(requestBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal json payload: %w", err)
-		// handle err
 	}
 	body := bytes.NewReader(requestBodyBytes)
 
-	req, err := http.NewRequest("POST", e.buildURL("/v1/oauth/token"), body)
+	req, err := e.newRequest(ctx, "POST", e.buildURL(oauthTokenPath), body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create authentication request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json;charset=UTF-8")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := e.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send authentication request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("invalid response code %d", resp.StatusCode)
-	}
-
 	authResponse := authResponse{}
 	err = json.NewDecoder(resp.Body).Decode(&authResponse)
 	if err != nil {
@@ -78,23 +130,169 @@ func (e *EseisClient) Authenticate() (*authToken, error) {
 	return token, nil
 }
 
-func (e *EseisClient) checkAuthenticated() error {
-	if e.accessToken == nil || e.accessToken.expiresAt.Add(-10*time.Minute).Before(time.Now()) {
-		token, err := e.Authenticate()
+// tokenRefresh is an in-flight token refresh shared by all the goroutines needing a new token
+type tokenRefresh struct {
+	done  chan struct{}
+	token *authToken
+	err   error
+}
+
+// isValid returns true if the token is not close to expiry
+func (t *authToken) isValid() bool {
+	return t != nil && t.expiresAt.Add(-10*time.Minute).After(time.Now())
+}
+
+// getToken returns a valid token, refreshing it when it is close to expiry.
+// Concurrent callers share a single in-flight refresh.
+func (e *EseisClient) getToken(ctx context.Context) (*authToken, error) {
+	for {
+		e.authMu.Lock()
+		if e.accessToken.isValid() {
+			token := e.accessToken
+			e.authMu.Unlock()
+			return token, nil
+		}
+		refresh := e.tokenRefresh
+		leader := refresh == nil
+		if leader {
+			refresh = &tokenRefresh{done: make(chan struct{})}
+			e.tokenRefresh = refresh
+		}
+		previousToken := e.accessToken
+		e.authMu.Unlock()
+
+		if leader {
+			refresh.token, refresh.err = e.renewToken(ctx, previousToken)
+			e.authMu.Lock()
+			if refresh.err == nil {
+				e.accessToken = refresh.token
+			}
+			e.tokenRefresh = nil
+			e.authMu.Unlock()
+			close(refresh.done)
+			if refresh.err == nil {
+				e.storeToken(refresh.token)
+			}
+			return refresh.token, refresh.err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-refresh.done:
+		}
+		if refresh.err == nil {
+			return refresh.token, nil
+		}
+		// the leader may have failed only because its own context was cancelled, try again with ours
+		if !errors.Is(refresh.err, context.Canceled) && !errors.Is(refresh.err, context.DeadlineExceeded) {
+			return nil, refresh.err
+		}
+	}
+}
+
+// renewToken obtains a new token, using the refresh token of the previous one if possible.
+// A password login is only performed when there is no previous token or when the refresh fails.
+func (e *EseisClient) renewToken(ctx context.Context, previousToken *authToken) (*authToken, error) {
+	if previousToken != nil && previousToken.refreshToken != "" {
+		token, err := e.refreshAuthentication(ctx, previousToken.refreshToken)
+		if err == nil {
+			return token, nil
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("failed to refresh authentication: %w", err)
+		}
+		logrus.Warnf("failed to refresh authentication, falling back to password login: %s", err)
+	}
+	token, err := e.Authenticate(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate: %w", err)
+	}
+	return token, nil
+}
+
+// storeToken persists the token in the token store if any
+func (e *EseisClient) storeToken(token *authToken) {
+	if e.tokenStore == nil {
+		return
+	}
+	err := e.tokenStore.Save(Token{
+		AccessToken:  token.accessToken,
+		RefreshToken: token.refreshToken,
+		ExpiresAt:    token.expiresAt,
+	})
+	if err != nil {
+		logrus.Warnf("failed to save token to cache: %s", err)
+	}
+}
+
+// loadStoredToken restores the token persisted by a previous run, if any
+func (e *EseisClient) loadStoredToken() {
+	if e.tokenStore == nil {
+		return
+	}
+	token, err := e.tokenStore.Load()
+	if err != nil {
+		logrus.Warnf("failed to load token from cache, ignoring it: %s", err)
+		return
+	}
+	if token == nil {
+		return
+	}
+	logrus.Debugf("loaded token from cache, expiring at %s", token.ExpiresAt)
+	e.authMu.Lock()
+	defer e.authMu.Unlock()
+	e.accessToken = &authToken{
+		accessToken:  token.AccessToken,
+		expiresAt:    token.ExpiresAt,
+		refreshToken: token.RefreshToken,
+	}
+}
+
+// invalidateToken drops the token if it is still the current one, keeping its refresh token for the renewal
+func (e *EseisClient) invalidateToken(accessToken string) {
+	e.authMu.Lock()
+	defer e.authMu.Unlock()
+	if e.accessToken != nil && e.accessToken.accessToken == accessToken {
+		e.accessToken = &authToken{refreshToken: e.accessToken.refreshToken}
+	}
+}
+
+// reauthenticate renews the token rejected for req and sets the new one on req.
+// It returns false if req was not authenticated with a token or cannot be sent again.
+func (e *EseisClient) reauthenticate(req *http.Request) bool {
+	authorization := req.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "Bearer ") || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		return false
+	}
+	accessToken := strings.TrimPrefix(authorization, "Bearer ")
+	logrus.Warn("eseis api rejected the token, renewing it")
+	e.invalidateToken(accessToken)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
 		if err != nil {
-			return fmt.Errorf("failed to authenticate: %w", err)
+			return false
 		}
-		e.accessToken = token
+		req.Body = body
 	}
-	return nil
+	if err := e.setAuthentication(req); err != nil {
+		logrus.Warnf("failed to renew the rejected token: %s", err)
+		return false
+	}
+	return true
 }
 
+// setAuthentication sets the token on the Authorization header of the request,
+// and on its access_token query parameter if it has one
 func (e *EseisClient) setAuthentication(request *http.Request) error {
-	if err := e.checkAuthenticated(); err != nil {
+	token, err := e.getToken(request.Context())
+	if err != nil {
 		return err
 	}
-	if e.accessToken != nil {
-		request.Header.Set("Authorization", "Bearer "+e.accessToken.accessToken)
+	request.Header.Set("Authorization", "Bearer "+token.accessToken)
+	if query := request.URL.Query(); query.Has("access_token") {
+		query.Set("access_token", token.accessToken)
+		request.URL.RawQuery = query.Encode()
 	}
 	return nil
 }
@@ -0,0 +1,320 @@
+package eseis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client of the Eseis API served by handler, without rate limiting nor browser
+func newTestClient(t *testing.T, handler http.Handler) *EseisClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client, err := NewEseisClient(
+		WithConfig(Config{
+			ClientId:   "client",
+			Username:   "user",
+			Password:   "password",
+			BaseURL:    server.URL,
+			BaseWebURL: server.URL,
+			RateLimits: RateLimits{RequestsPerSecond: -1},
+		}),
+		WithHTTPClient(server.Client()),
+		WithoutChrome(),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+	return client
+}
+
+func writeTestToken(t *testing.T, w http.ResponseWriter, accessToken string) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(authResponse{
+		AccessToken:  accessToken,
+		CreatedAt:    time.Now().Unix(),
+		ExpiresIn:    7200,
+		RefreshToken: "refresh-" + accessToken,
+		TokenType:    "Bearer",
+	})
+	if err != nil {
+		t.Errorf("failed to write token response: %s", err)
+	}
+}
+
+func TestGetTokenConcurrentCallersShareOneRefresh(t *testing.T) {
+	var tokenRequests int32
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != oauthTokenPath {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(&tokenRequests, 1)
+		// keep the refresh in flight while the other callers arrive
+		time.Sleep(50 * time.Millisecond)
+		writeTestToken(t, w, "token")
+	}))
+
+	const callers = 50
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			token, err := client.getToken(context.Background())
+			if err == nil && token.accessToken != "token" {
+				err = errors.New("unexpected access token " + token.accessToken)
+			}
+			errs <- err
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("getToken failed: %s", err)
+		}
+	}
+	if got := atomic.LoadInt32(&tokenRequests); got != 1 {
+		t.Errorf("got %d token requests, want 1", got)
+	}
+}
+
+func TestGetTokenFollowerRetriesWhenLeaderIsCancelled(t *testing.T) {
+	var tokenRequests int32
+	firstRequest := make(chan struct{})
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != oauthTokenPath {
+			http.NotFound(w, r)
+			return
+		}
+		if atomic.AddInt32(&tokenRequests, 1) == 1 {
+			// hang the request of the leader until it gives up, the server only notices the client going away once
+			// the request body is read
+			_, _ = io.Copy(io.Discard, r.Body)
+			close(firstRequest)
+			<-r.Context().Done()
+			return
+		}
+		writeTestToken(t, w, "token")
+	}))
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	defer cancelLeader()
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := client.getToken(leaderCtx)
+		leaderErr <- err
+	}()
+	<-firstRequest
+
+	followerErr := make(chan error, 1)
+	go func() {
+		token, err := client.getToken(context.Background())
+		if err == nil && token.accessToken != "token" {
+			err = errors.New("unexpected access token " + token.accessToken)
+		}
+		followerErr <- err
+	}()
+	// let the follower wait on the refresh of the leader before cancelling it
+	time.Sleep(50 * time.Millisecond)
+	cancelLeader()
+
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("got leader error %v, want context.Canceled", err)
+	}
+	select {
+	case err := <-followerErr:
+		if err != nil {
+			t.Errorf("follower getToken failed: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("follower did not get a token")
+	}
+	if got := atomic.LoadInt32(&tokenRequests); got != 2 {
+		t.Errorf("got %d token requests, want 2", got)
+	}
+}
+
+// tokenRequestBody is the union of the payloads of the oauth grants
+type tokenRequestBody struct {
+	GrantType    string `json:"grant_type"`
+	RefreshToken string `json:"refresh_token"`
+	Username     string `json:"username"`
+}
+
+func decodeTokenRequest(t *testing.T, r *http.Request) tokenRequestBody {
+	t.Helper()
+	var body tokenRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		t.Errorf("failed to decode token request: %s", err)
+	}
+	return body
+}
+
+func TestGetTokenGrants(t *testing.T) {
+	tests := []struct {
+		name          string
+		previous      *authToken
+		rejectRefresh bool
+		wantGrants    []string
+		wantToken     string
+	}{
+		{"no cached token", nil, false, []string{"password"}, "password-token"},
+		{"cached token without refresh token", &authToken{accessToken: "expired"}, false, []string{"password"}, "password-token"},
+		{"refresh accepted", &authToken{accessToken: "expired", refreshToken: "cached-refresh"}, false, []string{"refresh_token"}, "refreshed-token"},
+		{"refresh rejected", &authToken{accessToken: "expired", refreshToken: "cached-refresh"}, true, []string{"refresh_token", "password"}, "password-token"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var grants []string
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != oauthTokenPath {
+					http.NotFound(w, r)
+					return
+				}
+				body := decodeTokenRequest(t, r)
+				grants = append(grants, body.GrantType)
+				switch body.GrantType {
+				case "refresh_token":
+					if body.RefreshToken != "cached-refresh" {
+						t.Errorf("got refresh token %q, want the cached one", body.RefreshToken)
+					}
+					if test.rejectRefresh {
+						http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+						return
+					}
+					writeTestToken(t, w, "refreshed-token")
+				case "password":
+					if body.Username != "user" {
+						t.Errorf("got username %q, want the configured one", body.Username)
+					}
+					writeTestToken(t, w, "password-token")
+				default:
+					t.Errorf("unexpected grant type %q", body.GrantType)
+					http.Error(w, `{"error":"unsupported_grant_type"}`, http.StatusBadRequest)
+				}
+			}))
+			if test.previous != nil {
+				test.previous.expiresAt = time.Now().Add(-time.Hour)
+				client.accessToken = test.previous
+			}
+
+			token, err := client.getToken(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token.accessToken != test.wantToken {
+				t.Errorf("got token %s, want %s", token.accessToken, test.wantToken)
+			}
+			if !reflect.DeepEqual(grants, test.wantGrants) {
+				t.Errorf("got grants %v, want %v", grants, test.wantGrants)
+			}
+		})
+	}
+}
+
+func TestRevokedTokenIsRenewedOnce(t *testing.T) {
+	var tokenRequests, apiRequests int32
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == oauthTokenPath {
+			atomic.AddInt32(&tokenRequests, 1)
+			// the refresh token of the revoked token is still valid
+			if body := decodeTokenRequest(t, r); body.GrantType != "refresh_token" || body.RefreshToken != "refresh-revoked" {
+				t.Errorf("got grant %s with refresh token %q, want the refresh of the revoked token", body.GrantType, body.RefreshToken)
+			}
+			writeTestToken(t, w, "renewed")
+			return
+		}
+		atomic.AddInt32(&apiRequests, 1)
+		if r.Header.Get("Authorization") != "Bearer renewed" {
+			http.Error(w, "revoked token", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, "[]")
+	}))
+	// a cached token revoked by the server before its expiry
+	client.accessToken = &authToken{accessToken: "revoked", expiresAt: time.Now().Add(time.Hour), refreshToken: "refresh-revoked"}
+
+	if _, err := client.GetMaintenanceContractCategories(context.Background(), 1); err != nil {
+		t.Fatalf("request with a revoked token failed: %s", err)
+	}
+	if got := atomic.LoadInt32(&tokenRequests); got != 1 {
+		t.Errorf("got %d token requests, want 1", got)
+	}
+	if got := atomic.LoadInt32(&apiRequests); got != 2 {
+		t.Errorf("got %d api requests, want 2", got)
+	}
+}
+
+func TestAuthenticateRejectedCredentials(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == oauthTokenPath {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+
+	_, err := client.GetMaintenanceContractCategories(context.Background(), 1)
+	if !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("got error %v, want ErrInvalidCredentials", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("got error %v, want the APIError of the token request", err)
+	}
+}
+
+func TestRevokedTokenIsRenewedOnDocumentDownload(t *testing.T) {
+	var tokenRequests, documentRequests int32
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == oauthTokenPath {
+			atomic.AddInt32(&tokenRequests, 1)
+			writeTestToken(t, w, "renewed")
+			return
+		}
+		atomic.AddInt32(&documentRequests, 1)
+		if r.URL.Query().Get("access_token") != "renewed" || r.Header.Get("Authorization") != "Bearer renewed" {
+			http.Error(w, "revoked token", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("uuid") != "document-uuid" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = io.WriteString(w, "document")
+	}))
+	client.accessToken = &authToken{accessToken: "revoked", expiresAt: time.Now().Add(time.Hour), refreshToken: "refresh-revoked"}
+
+	var buffer bytes.Buffer
+	if _, err := client.DownloadDocument(context.Background(), "document-uuid", &buffer); err != nil {
+		t.Fatalf("download with a revoked token failed: %s", err)
+	}
+	if buffer.String() != "document" {
+		t.Errorf("got document %q, want %q", buffer.String(), "document")
+	}
+	if got := atomic.LoadInt32(&tokenRequests); got != 1 {
+		t.Errorf("got %d token requests, want 1", got)
+	}
+	if got := atomic.LoadInt32(&documentRequests); got != 2 {
+		t.Errorf("got %d document requests, want 2", got)
+	}
+}
@@ -1,9 +1,9 @@
 package eseis
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
 	"time"
 )
 
@@ -21,9 +21,9 @@ type FiscalYear struct {
 	EndDate     time.Time
 }
 
-func (e *EseisClient) GetFiscalYears(placeID int) ([]FiscalYear, error) {
+func (e *EseisClient) GetFiscalYears(ctx context.Context, placeID int) ([]FiscalYear, error) {
 	path := fmt.Sprintf("/v1/places/%d/fiscal_years", placeID)
-	req, err := http.NewRequest("GET", e.buildURL(path), nil)
+	req, err := e.newRequest(ctx, "GET", e.buildURL(path), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create fiscal_years request: %w", err)
 	}
@@ -31,7 +31,7 @@ func (e *EseisClient) GetFiscalYears(placeID int) ([]FiscalYear, error) {
 		return nil, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := e.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send fiscal_years request: %w", err)
 	}
@@ -69,9 +69,9 @@ type Budget struct {
 	SpentAmount     int
 }
 
-func (e *EseisClient) GetBudgets(placeID int, fiscalYearID int) ([]Budget, error) {
+func (e *EseisClient) GetBudgets(ctx context.Context, placeID int, fiscalYearID int) ([]Budget, error) {
 	path := fmt.Sprintf("/v1/places/%d/budgets?fiscal_year_id=%d", placeID, fiscalYearID)
-	req, err := http.NewRequest("GET", e.buildURL(path), nil)
+	req, err := e.newRequest(ctx, "GET", e.buildURL(path), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create budgets request: %w", err)
 	}
@@ -79,7 +79,7 @@ func (e *EseisClient) GetBudgets(placeID int, fiscalYearID int) ([]Budget, error
 		return nil, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := e.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send budgets request: %w", err)
 	}
@@ -126,20 +126,15 @@ type AccountPlaceEntry struct {
 	Amount              int
 	OperationDate       time.Time
 	UpdatedAt           time.Time
-	Attachment          *AccountPlaceEntryAttachment
+	Attachment          *Attachment
 	ProviderID          int
 	ProviderDisplayName string
 	DisplayState        string
 }
 
-type AccountPlaceEntryAttachment struct {
-	DisplayName string
-	FileURL     string
-}
-
-func (e *EseisClient) GetAccountPlaceEntries(budgetID int) ([]AccountPlaceEntry, error) {
+func (e *EseisClient) GetAccountPlaceEntries(ctx context.Context, budgetID int) ([]AccountPlaceEntry, error) {
 	path := fmt.Sprintf("/v1/budgets/%d/account_place_entries", budgetID)
-	req, err := http.NewRequest("GET", e.buildURL(path), nil)
+	req, err := e.newRequest(ctx, "GET", e.buildURL(path), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -147,7 +142,7 @@ func (e *EseisClient) GetAccountPlaceEntries(budgetID int) ([]AccountPlaceEntry,
 		return nil, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := e.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -168,9 +163,10 @@ func (e *EseisClient) GetAccountPlaceEntries(budgetID int) ([]AccountPlaceEntry,
 			Amount:        responseElement.Amount,
 			OperationDate: responseElement.OperationDate,
 			UpdatedAt:     responseElement.UpdatedAt,
-			Attachment: &AccountPlaceEntryAttachment{
-				DisplayName: responseElement.Attachment.DisplayName,
-				FileURL:     responseElement.Attachment.FileURL,
+			// the API gives neither the update date nor the id of the attachment file
+			Attachment: &Attachment{
+				FileName: responseElement.Attachment.DisplayName,
+				FileURL:  responseElement.Attachment.FileURL,
 			},
 			ProviderID:          responseElement.ProviderID,
 			ProviderDisplayName: responseElement.ProviderDisplayName,
@@ -0,0 +1,160 @@
+package eseis
+
+import (
+	"context"
+	"fmt"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/utils"
+	"net/url"
+	"path/filepath"
+	"time"
+)
+
+// CaptureFormat is a file format of the web page captures
+type CaptureFormat string
+
+const (
+	// CapturePDF prints the page with the print options
+	CapturePDF CaptureFormat = "pdf"
+	// CapturePNG is a pixel exact screenshot of the full page
+	CapturePNG CaptureFormat = "png"
+	// CaptureMHTML is a self-contained snapshot of the page with its resources
+	CaptureMHTML CaptureFormat = "mhtml"
+)
+
+// Extension returns the file extension of the format
+func (f CaptureFormat) Extension() string {
+	return "." + string(f)
+}
+
+func (f CaptureFormat) validate() error {
+	switch f {
+	case CapturePDF, CapturePNG, CaptureMHTML:
+		return nil
+	}
+	return fmt.Errorf("unknown capture format %q", string(f))
+}
+
+// Capture loads the page at URL, runs actions then writes a capture for each format to basePath followed by the
+// format extension. itemID is the id of the Eseis item stamped on the pdf.
+func (e *EseisClient) Capture(ctx context.Context, URL string, itemID string, basePath string, formats []CaptureFormat, actions ...chromedp.Action) error {
+	outPaths := make(map[CaptureFormat]string, len(formats))
+	for _, format := range formats {
+		outPaths[format] = basePath + format.Extension()
+	}
+	return e.capture(ctx, URL, itemID, outPaths, actions...)
+}
+
+// SavePDF prints the page at URL to outPath once actions are done, itemID is the id of the Eseis item stamped on the pdf
+func (e *EseisClient) SavePDF(ctx context.Context, URL string, itemID string, outPath string, actions ...chromedp.Action) error {
+	return e.capture(ctx, URL, itemID, map[CaptureFormat]string{CapturePDF: outPath}, actions...)
+}
+
+func (e *EseisClient) capture(ctx context.Context, URL string, itemID string, outPaths map[CaptureFormat]string, actions ...chromedp.Action) error {
+	if len(outPaths) == 0 {
+		return nil
+	}
+	formats, err := captureOrder(outPaths)
+	if err != nil {
+		return err
+	}
+	// concurrent captures would navigate the tab away from each other's page
+	e.tabMu.Lock()
+	defer e.tabMu.Unlock()
+	chromeSession, err := e.webUIBrowser(ctx)
+	if err != nil {
+		return err
+	}
+
+	var captureActions []chromedp.Action
+	captureActions = append(captureActions, navigateAction(URL))
+	captureActions = append(captureActions, actions...)
+	captures := make(map[CaptureFormat]*[]byte, len(outPaths))
+	for _, format := range formats {
+		buf := &[]byte{}
+		captures[format] = buf
+		switch format {
+		case CapturePDF:
+			stamp := PrintStamp{URL: URL, ItemID: itemID, CapturedAt: time.Now()}
+			captureActions = append(captureActions, printPdfAction(e.config.Print, stamp, buf))
+		case CapturePNG:
+			// a quality of 100 captures a png instead of a jpeg
+			captureActions = append(captureActions, chromedp.FullScreenshot(buf, 100))
+		case CaptureMHTML:
+			captureActions = append(captureActions, captureSnapshotAction(buf))
+		}
+	}
+
+	if err := chromeSession.RunTasks(ctx, captureActions); err != nil {
+		return fmt.Errorf("failed to capture %s: %w", URL, err)
+	}
+	for _, format := range formats {
+		outPath := outPaths[format]
+		if err := utils.WriteBytesAtomic(outPath, *captures[format], 0660); err != nil {
+			return fmt.Errorf("failed to write %s capture to %s: %w", format, outPath, err)
+		}
+	}
+	return nil
+}
+
+// PrintHTMLFile prints the local html file at htmlPath to a pdf at outPath with the print options. The browser does
+// not log in to the web UI. A remote browser must see the file at the same path.
+func (e *EseisClient) PrintHTMLFile(ctx context.Context, htmlPath string, outPath string, stamp PrintStamp) error {
+	absPath, err := filepath.Abs(htmlPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path of %s: %w", htmlPath, err)
+	}
+	e.tabMu.Lock()
+	defer e.tabMu.Unlock()
+	chromeSession, err := e.browser(ctx)
+	if err != nil {
+		return err
+	}
+	if stamp.CapturedAt.IsZero() {
+		stamp.CapturedAt = time.Now()
+	}
+
+	var buf []byte
+	fileURL := (&url.URL{Scheme: "file", Path: filepath.ToSlash(absPath)}).String()
+	actions := []chromedp.Action{
+		navigateAction(fileURL),
+		e.waitPageReadyAction(),
+		printPdfAction(e.config.Print, stamp, &buf),
+	}
+	if err = chromeSession.RunTasks(ctx, actions); err != nil {
+		return fmt.Errorf("failed to print %s: %w", htmlPath, err)
+	}
+	if err = utils.WriteBytesAtomic(outPath, buf, 0660); err != nil {
+		return fmt.Errorf("failed to write pdf of %s to %s: %w", htmlPath, outPath, err)
+	}
+	return nil
+}
+
+// captureOrder returns the formats of outPaths in their capture order, the screen formats before the print one which
+// switches the page to print media
+func captureOrder(outPaths map[CaptureFormat]string) ([]CaptureFormat, error) {
+	for format := range outPaths {
+		if err := format.validate(); err != nil {
+			return nil, err
+		}
+	}
+	var formats []CaptureFormat
+	for _, format := range []CaptureFormat{CapturePNG, CaptureMHTML, CapturePDF} {
+		if _, ok := outPaths[format]; ok {
+			formats = append(formats, format)
+		}
+	}
+	return formats, nil
+}
+
+func captureSnapshotAction(res *[]byte) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		snapshot, err := page.CaptureSnapshot().WithFormat(page.CaptureSnapshotFormatMhtml).Do(ctx)
+		if err != nil {
+			return err
+		}
+		*res = []byte(snapshot)
+		return nil
+	})
+}
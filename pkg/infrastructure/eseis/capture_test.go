@@ -0,0 +1,140 @@
+package eseis
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newBrowserTestClient returns a client with a started browser, skipping the test when no browser is found.
+// The browser is ESEIS_CHROME_EXEC_PATH or the first chrome found in the PATH.
+func newBrowserTestClient(t *testing.T, opts ...Option) *EseisClient {
+	execPath := os.Getenv("ESEIS_CHROME_EXEC_PATH")
+	for _, name := range []string{"chromium", "chromium-browser", "google-chrome", "chrome", "headless-shell"} {
+		if execPath != "" {
+			break
+		}
+		execPath, _ = exec.LookPath(name)
+	}
+	if execPath == "" {
+		t.Skip("no browser found, set ESEIS_CHROME_EXEC_PATH to run this test")
+	}
+	config := testConfig()
+	config.Chrome.ExecPath = execPath
+	config.Chrome.Flags = []string{"no-sandbox"}
+	config.Readiness = Readiness{QuietPeriod: 10 * time.Millisecond, Timeout: 5 * time.Second}
+	client, err := NewEseisClient(append([]Option{WithConfig(config)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err = client.browser(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestCaptureOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		formats []CaptureFormat
+		want    []CaptureFormat
+		wantErr bool
+	}{
+		{"pdf", []CaptureFormat{CapturePDF}, []CaptureFormat{CapturePDF}, false},
+		{"screen formats first", []CaptureFormat{CapturePDF, CaptureMHTML, CapturePNG}, []CaptureFormat{CapturePNG, CaptureMHTML, CapturePDF}, false},
+		{"subset", []CaptureFormat{CapturePDF, CapturePNG}, []CaptureFormat{CapturePNG, CapturePDF}, false},
+		{"unknown format", []CaptureFormat{CapturePDF, "jpeg"}, nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			outPaths := make(map[CaptureFormat]string)
+			for _, format := range test.formats {
+				outPaths[format] = "capture" + format.Extension()
+			}
+			got, err := captureOrder(outPaths)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %t", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got formats %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCaptureRejectsUnknownFormatsBeforeStartingTheBrowser(t *testing.T) {
+	client, err := NewEseisClient(WithConfig(testConfig()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	basePath := filepath.Join(t.TempDir(), "report")
+	err = client.Capture(context.Background(), "http://eseis/reports/1", "1", basePath, []CaptureFormat{CapturePDF, "jpeg"})
+	if err == nil || !strings.Contains(err.Error(), "unknown capture format") {
+		t.Errorf("got error %v, want an unknown format error", err)
+	}
+	if client.chromeSession != nil || client.chromeErr != nil {
+		t.Error("the browser was started for an unknown format")
+	}
+}
+
+func TestCaptureWritesTheRequestedFormats(t *testing.T) {
+	client := newBrowserTestClient(t)
+	// the capture does not need the web UI session
+	client.chromeLoggedIn = true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><body><h1>Report</h1></body></html>")
+	}))
+	defer server.Close()
+
+	magics := map[CaptureFormat][]byte{
+		CapturePDF:   []byte("%PDF"),
+		CapturePNG:   []byte("\x89PNG"),
+		CaptureMHTML: []byte("From: <Saved by Blink>"),
+	}
+	tests := []struct {
+		name    string
+		formats []CaptureFormat
+	}{
+		{"pdf", []CaptureFormat{CapturePDF}},
+		{"png and pdf", []CaptureFormat{CapturePDF, CapturePNG}},
+		{"all", []CaptureFormat{CaptureMHTML, CapturePDF, CapturePNG}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			basePath := filepath.Join(t.TempDir(), "report")
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := client.Capture(ctx, server.URL, "1", basePath, test.formats); err != nil {
+				t.Fatal(err)
+			}
+			for format, magic := range magics {
+				content, err := os.ReadFile(basePath + format.Extension())
+				requested := false
+				for _, f := range test.formats {
+					requested = requested || f == format
+				}
+				switch {
+				case !requested && !errors.Is(err, os.ErrNotExist):
+					t.Errorf("got %s capture with error %v, want none", format, err)
+				case requested && err != nil:
+					t.Errorf("got error %v, want a %s capture", err, format)
+				case requested && !bytes.HasPrefix(content, magic):
+					t.Errorf("got a %s capture of %d bytes not starting with %q", format, len(content), magic)
+				}
+			}
+		})
+	}
+}
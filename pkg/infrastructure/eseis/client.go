@@ -1,17 +1,46 @@
 package eseis
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/caarlos0/env/v7"
 	"github.com/idkw/eseisscrapper/pkg/infrastructure/chrome"
 	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"sync"
 )
 
-// EseisClient is a client for the Eseis API
+const (
+	defaultBaseURL    = "https://sergic-api-prod.sergic.com"
+	defaultBaseWebURL = "https://client.eseis-syndic.com"
+)
+
+// ErrChromeDisabled is returned by browser based methods when the client was built without chrome
+var ErrChromeDisabled = errors.New("chrome is disabled for this eseis client")
+
+// EseisClient is a client for the Eseis API, safe for concurrent use
 type EseisClient struct {
-	config        *Config
-	accessToken   *authToken
-	chromeSession *chrome.Chrome
+	config         *Config
+	httpClient     *http.Client
+	transport      http.RoundTripper
+	userAgent      string
+	authMu         sync.Mutex // guards accessToken and tokenRefresh
+	accessToken    *authToken
+	tokenRefresh   *tokenRefresh
+	tokenStore     TokenStore
+	limiter        *rateLimiter
+	tabMu          sync.Mutex // serialises the pages loaded in the single browser tab, acquired before chromeMu
+	chromeMu       sync.Mutex // guards the chrome session, its login and network
+	chromeDisabled bool
+	chromeSession  *chrome.Chrome
+	chromeErr      error
+	chromeLoggedIn bool
+	loginErr       error
+	network        *networkTracker
+	console        *consoleRecorder
+	selectors      *SelectorProfile
 }
 
 // Config is a configuration struct to build an EseisClient
@@ -21,24 +50,89 @@ type Config struct {
 	Password   string `env:"ESEIS_PASSWORD,required"`
 	BaseURL    string `env:"ESEIS_BASE_URL,required" envDefault:"https://sergic-api-prod.sergic.com"`
 	BaseWebURL string `env:"ESEIS_BASE_WEB_URL,required" envDefault:"https://client.eseis-syndic.com"`
+	Retry      RetryPolicy
+	RateLimits RateLimits
+	// TokenCacheKey enables the encrypted token cache when set, it is the secret the cache encryption key is derived from
+	TokenCacheKey string `env:"ESEIS_TOKEN_CACHE_KEY"`
+	// TokenCacheFile is the token cache path, defaults to a file under the user cache dir
+	TokenCacheFile string `env:"ESEIS_TOKEN_CACHE_FILE"`
+	// ChromeDisabled disables all browser work, screenshots then fail with ErrChromeDisabled
+	ChromeDisabled bool `env:"ESEIS_CHROME_DISABLED"`
+	// Chrome configures the browser used for screenshots
+	Chrome chrome.Config
+	// Readiness configures when a page is ready for its screenshot
+	Readiness Readiness
+	// Print configures the pdf captures
+	Print PrintOptions
+	// CaptureFormats are the formats of the report and forum topic captures
+	CaptureFormats []CaptureFormat `env:"ESEIS_CAPTURE_FORMATS" envSeparator:"," envDefault:"pdf"`
+	// SelectorProfile is the path of a json selector profile overriding the default selectors of the web UI
+	SelectorProfile string `env:"ESEIS_SELECTOR_PROFILE"`
+	// FormLoginOnly makes the browser log in with the login form instead of starting the session from the API token
+	FormLoginOnly bool `env:"ESEIS_CHROME_FORM_LOGIN_ONLY"`
+	// DiagnosticsDir receives the screenshot, html and console logs of the page when the web UI login fails,
+	// defaults to a dir under the user cache dir
+	DiagnosticsDir string `env:"ESEIS_DIAGNOSTICS_DIR"`
+}
+
+// NewEseisClient creates a new EseisClient from the given options or returns an error.
+// WithConfig is mandatory.
+// The browser is only started by the first screenshot, see Close.
+func NewEseisClient(opts ...Option) (*EseisClient, error) {
+	client := &EseisClient{httpClient: http.DefaultClient}
+	for _, opt := range opts {
+		opt(client)
+	}
+	if client.config == nil {
+		return nil, errors.New("missing eseis client config")
+	}
+	if client.transport != nil {
+		httpClient := *client.httpClient
+		httpClient.Transport = client.transport
+		client.httpClient = &httpClient
+	}
+	client.config.setDefaults()
+	if err := client.config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid eseis client config: %w", err)
+	}
+	client.limiter = newRateLimiter(client.config.RateLimits)
+	if client.tokenStore == nil && client.config.TokenCacheKey != "" {
+		tokenStore, err := newConfigTokenStore(client.config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create token cache: %w", err)
+		}
+		client.tokenStore = tokenStore
+	}
+	client.loadStoredToken()
+	if client.config.ChromeDisabled {
+		client.chromeDisabled = true
+	}
+	if client.selectors == nil {
+		selectors, err := newConfigSelectorProfile(client.config)
+		if err != nil {
+			return nil, err
+		}
+		client.selectors = selectors
+	}
+	if err := client.selectors.validate(); err != nil {
+		return nil, fmt.Errorf("invalid selector profile: %w", err)
+	}
+	return client, nil
 }
 
-// NewEseisClient creates a new EseisClient or returns an error
-func NewEseisClient() (*EseisClient, error) {
+// NewEseisClientFromEnv creates a new EseisClient configured from environment variables or returns an error.
+// Additional options are applied after the environment configuration.
+func NewEseisClientFromEnv(opts ...Option) (*EseisClient, error) {
 	config, err := newConfig()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create eseis client config: %w", err)
 	}
-	chromeSession, err := newChrome(config.BaseWebURL, config.Username, config.Password)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create chrome instance: %s", err)
-	}
-	return &EseisClient{config: config, chromeSession: chromeSession}, nil
+	return NewEseisClient(append([]Option{WithConfig(*config)}, opts...)...)
 }
 
-// NewEseisClientFatal creates a new EseisClient or panics if an errors occurs
-func NewEseisClientFatal() *EseisClient {
-	client, err := NewEseisClient()
+// NewEseisClientFatal creates a new EseisClient configured from environment variables or panics if an errors occurs
+func NewEseisClientFatal(opts ...Option) *EseisClient {
+	client, err := NewEseisClientFromEnv(opts...)
 	if err != nil {
 		logrus.Fatalf("failed to create eseis client: %s", err)
 	}
@@ -53,6 +147,66 @@ func newConfig() (*Config, error) {
 	return config, nil
 }
 
+func newConfigTokenStore(config *Config) (*FileTokenStore, error) {
+	path := config.TokenCacheFile
+	if path == "" {
+		var err error
+		if path, err = defaultTokenCachePath(config); err != nil {
+			return nil, err
+		}
+	}
+	return NewFileTokenStore(path, config.TokenCacheKey)
+}
+
+func newConfigSelectorProfile(config *Config) (*SelectorProfile, error) {
+	if config.SelectorProfile == "" {
+		return DefaultSelectorProfile()
+	}
+	return LoadSelectorProfile(config.SelectorProfile)
+}
+
+func (c *Config) setDefaults() {
+	if c.BaseURL == "" {
+		c.BaseURL = defaultBaseURL
+	}
+	if c.BaseWebURL == "" {
+		c.BaseWebURL = defaultBaseWebURL
+	}
+	c.Retry.setDefaults()
+	c.RateLimits.setDefaults()
+	c.Readiness.setDefaults()
+	c.Print.setDefaults()
+	if len(c.CaptureFormats) == 0 {
+		c.CaptureFormats = []CaptureFormat{CapturePDF}
+	}
+}
+
+func (c *Config) validate() error {
+	if c.ClientId == "" || c.Username == "" || c.Password == "" {
+		return errors.New("client id, username and password are required")
+	}
+	if err := c.Print.validate(); err != nil {
+		return err
+	}
+	for _, format := range c.CaptureFormats {
+		if err := format.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *EseisClient) newRequest(ctx context.Context, method string, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if e.userAgent != "" {
+		req.Header.Set("User-Agent", e.userAgent)
+	}
+	return req, nil
+}
+
 func (e *EseisClient) buildURL(path string) string {
 	return e.config.BaseURL + path
 }
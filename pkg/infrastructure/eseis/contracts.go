@@ -1,9 +1,9 @@
 package eseis
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
 	"time"
 )
 
@@ -19,9 +19,9 @@ type Contract struct {
 	PlaceID     int
 }
 
-func (e *EseisClient) GetContracts(sergicOffer string) ([]Contract, error) {
+func (e *EseisClient) GetContracts(ctx context.Context, sergicOffer string) ([]Contract, error) {
 	path := fmt.Sprintf("/v1/users/me/contracts?by_sergic_offer=%s", sergicOffer)
-	req, err := http.NewRequest("GET", e.buildURL(path), nil)
+	req, err := e.newRequest(ctx, "GET", e.buildURL(path), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create contracts request: %w", err)
 	}
@@ -29,7 +29,7 @@ func (e *EseisClient) GetContracts(sergicOffer string) ([]Contract, error) {
 		return nil, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := e.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send contracts request: %w", err)
 	}
@@ -63,9 +63,27 @@ type ContractFolder struct {
 	DisplayName string
 }
 
-func (e *EseisClient) GetContractFolders(contractID int, page int) ([]ContractFolder, error) {
-	path := fmt.Sprintf("/v2/contract_folders?by_contract=%d&page=%d&sort=display_name", contractID, page)
-	req, err := http.NewRequest("GET", e.buildURL(path), nil)
+// GetContractFolders returns a page of the folders of a contract, pages start at 1. See IterContractFolders to go through all of them.
+func (e *EseisClient) GetContractFolders(ctx context.Context, contractID int, page int) ([]ContractFolder, error) {
+	return e.getContractFoldersPage(ctx, contractID, page, 0)
+}
+
+// IterContractFolders returns a Pager over all the folders of a contract
+func (e *EseisClient) IterContractFolders(contractID int, opts ...PagerOption) *Pager[ContractFolder] {
+	fetch := func(ctx context.Context, page int, perPage int) ([]ContractFolder, error) {
+		return e.getContractFoldersPage(ctx, contractID, page, perPage)
+	}
+	return NewPager(fetch, opts...)
+}
+
+// AllContractFolders returns all the folders of a contract
+func (e *EseisClient) AllContractFolders(ctx context.Context, contractID int, opts ...PagerOption) ([]ContractFolder, error) {
+	return e.IterContractFolders(contractID, opts...).All(ctx)
+}
+
+func (e *EseisClient) getContractFoldersPage(ctx context.Context, contractID int, page int, perPage int) ([]ContractFolder, error) {
+	path := fmt.Sprintf("/v2/contract_folders?by_contract=%d&%s&sort=display_name", contractID, pageQuery(page, perPage))
+	req, err := e.newRequest(ctx, "GET", e.buildURL(path), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create contract_folders request: %w", err)
 	}
@@ -73,7 +91,7 @@ func (e *EseisClient) GetContractFolders(contractID int, page int) ([]ContractFo
 		return nil, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := e.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send contract_folders request: %w", err)
 	}
@@ -102,17 +120,31 @@ type contractDocumentResponse struct {
 }
 
 type ContractDocument struct {
-	ID            int
-	UUID          string
-	DisplayName   string
+	DocumentRef
 	DocumentsName string
-	FileURL       string
-	UpdatedAt     time.Time
 }
 
-func (e *EseisClient) GetContractDocuments(contractID int, folderID, page int) ([]ContractDocument, error) {
-	path := fmt.Sprintf("/v1/contracts/%d/contract_documents?by_folder=%d&page=%d", contractID, folderID, page)
-	req, err := http.NewRequest("GET", e.buildURL(path), nil)
+// GetContractDocuments returns a page of the documents of a contract folder, pages start at 1. See IterContractDocuments to go through all of them.
+func (e *EseisClient) GetContractDocuments(ctx context.Context, contractID int, folderID int, page int) ([]ContractDocument, error) {
+	return e.getContractDocumentsPage(ctx, contractID, folderID, page, 0)
+}
+
+// IterContractDocuments returns a Pager over all the documents of a contract folder
+func (e *EseisClient) IterContractDocuments(contractID int, folderID int, opts ...PagerOption) *Pager[ContractDocument] {
+	fetch := func(ctx context.Context, page int, perPage int) ([]ContractDocument, error) {
+		return e.getContractDocumentsPage(ctx, contractID, folderID, page, perPage)
+	}
+	return NewPager(fetch, opts...)
+}
+
+// AllContractDocuments returns all the documents of a contract folder
+func (e *EseisClient) AllContractDocuments(ctx context.Context, contractID int, folderID int, opts ...PagerOption) ([]ContractDocument, error) {
+	return e.IterContractDocuments(contractID, folderID, opts...).All(ctx)
+}
+
+func (e *EseisClient) getContractDocumentsPage(ctx context.Context, contractID int, folderID int, page int, perPage int) ([]ContractDocument, error) {
+	path := fmt.Sprintf("/v1/contracts/%d/contract_documents?by_folder=%d&%s", contractID, folderID, pageQuery(page, perPage))
+	req, err := e.newRequest(ctx, "GET", e.buildURL(path), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create contract_documents request: %w", err)
 	}
@@ -120,7 +152,7 @@ func (e *EseisClient) GetContractDocuments(contractID int, folderID, page int) (
 		return nil, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := e.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send contract_documents request: %w", err)
 	}
@@ -135,12 +167,14 @@ func (e *EseisClient) GetContractDocuments(contractID int, folderID, page int) (
 	var contractDocuments = make([]ContractDocument, len(contractDocumentsResponse))
 	for i, contractDocument := range contractDocumentsResponse {
 		contractDocuments[i] = ContractDocument{
-			ID:            contractDocument.ID,
-			UUID:          contractDocument.UUID,
-			DisplayName:   contractDocument.DisplayName,
+			DocumentRef: DocumentRef{
+				ID:          contractDocument.ID,
+				UUID:        contractDocument.UUID,
+				DisplayName: contractDocument.DisplayName,
+				FileURL:     contractDocument.FileURL,
+				UpdatedAt:   contractDocument.UpdatedAt,
+			},
 			DocumentsName: contractDocument.DocumentsName,
-			FileURL:       contractDocument.FileURL,
-			UpdatedAt:     contractDocument.UpdatedAt,
 		}
 	}
 	return contractDocuments, nil
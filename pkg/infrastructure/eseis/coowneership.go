@@ -1,9 +1,9 @@
 package eseis
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
 	"time"
 )
 
@@ -18,9 +18,27 @@ type CoownershipFolder struct {
 	DisplayName string
 }
 
-func (e *EseisClient) GetCoownershipFolders(placeID int, page int) ([]CoownershipFolder, error) {
-	path := fmt.Sprintf("/v2/places/%d/coownership_folders?page=%d&sort=display_name", placeID, page)
-	req, err := http.NewRequest("GET", e.buildURL(path), nil)
+// GetCoownershipFolders returns a page of the coownership folders of a place, pages start at 1. See IterCoownershipFolders to go through all of them.
+func (e *EseisClient) GetCoownershipFolders(ctx context.Context, placeID int, page int) ([]CoownershipFolder, error) {
+	return e.getCoownershipFoldersPage(ctx, placeID, page, 0)
+}
+
+// IterCoownershipFolders returns a Pager over all the coownership folders of a place
+func (e *EseisClient) IterCoownershipFolders(placeID int, opts ...PagerOption) *Pager[CoownershipFolder] {
+	fetch := func(ctx context.Context, page int, perPage int) ([]CoownershipFolder, error) {
+		return e.getCoownershipFoldersPage(ctx, placeID, page, perPage)
+	}
+	return NewPager(fetch, opts...)
+}
+
+// AllCoownershipFolders returns all the coownership folders of a place
+func (e *EseisClient) AllCoownershipFolders(ctx context.Context, placeID int, opts ...PagerOption) ([]CoownershipFolder, error) {
+	return e.IterCoownershipFolders(placeID, opts...).All(ctx)
+}
+
+func (e *EseisClient) getCoownershipFoldersPage(ctx context.Context, placeID int, page int, perPage int) ([]CoownershipFolder, error) {
+	path := fmt.Sprintf("/v2/places/%d/coownership_folders?%s&sort=display_name", placeID, pageQuery(page, perPage))
+	req, err := e.newRequest(ctx, "GET", e.buildURL(path), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create coownership_folders request: %w", err)
 	}
@@ -28,7 +46,7 @@ func (e *EseisClient) GetCoownershipFolders(placeID int, page int) ([]Coownershi
 		return nil, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := e.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send coownership_folders request: %w", err)
 	}
@@ -57,17 +75,31 @@ type coownershipDocumentResponse struct {
 }
 
 type CoownershipDocument struct {
-	ID            int
-	UUID          string
-	DisplayName   string
+	DocumentRef
 	DocumentsName string
-	FileURL       string
-	UpdatedAt     time.Time
 }
 
-func (e *EseisClient) GetCoownershipDocuments(placeID int, folderID int, page int) ([]CoownershipDocument, error) {
-	path := fmt.Sprintf("/v1/places/%d/coownership_documents?by_folder=%d&page=%d", placeID, folderID, page)
-	req, err := http.NewRequest("GET", e.buildURL(path), nil)
+// GetCoownershipDocuments returns a page of the documents of a coownership folder, pages start at 1. See IterCoownershipDocuments to go through all of them.
+func (e *EseisClient) GetCoownershipDocuments(ctx context.Context, placeID int, folderID int, page int) ([]CoownershipDocument, error) {
+	return e.getCoownershipDocumentsPage(ctx, placeID, folderID, page, 0)
+}
+
+// IterCoownershipDocuments returns a Pager over all the documents of a coownership folder
+func (e *EseisClient) IterCoownershipDocuments(placeID int, folderID int, opts ...PagerOption) *Pager[CoownershipDocument] {
+	fetch := func(ctx context.Context, page int, perPage int) ([]CoownershipDocument, error) {
+		return e.getCoownershipDocumentsPage(ctx, placeID, folderID, page, perPage)
+	}
+	return NewPager(fetch, opts...)
+}
+
+// AllCoownershipDocuments returns all the documents of a coownership folder
+func (e *EseisClient) AllCoownershipDocuments(ctx context.Context, placeID int, folderID int, opts ...PagerOption) ([]CoownershipDocument, error) {
+	return e.IterCoownershipDocuments(placeID, folderID, opts...).All(ctx)
+}
+
+func (e *EseisClient) getCoownershipDocumentsPage(ctx context.Context, placeID int, folderID int, page int, perPage int) ([]CoownershipDocument, error) {
+	path := fmt.Sprintf("/v1/places/%d/coownership_documents?by_folder=%d&%s", placeID, folderID, pageQuery(page, perPage))
+	req, err := e.newRequest(ctx, "GET", e.buildURL(path), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create coownership_documents request: %w", err)
 	}
@@ -75,7 +107,7 @@ func (e *EseisClient) GetCoownershipDocuments(placeID int, folderID int, page in
 		return nil, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := e.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send coownership_documents request: %w", err)
 	}
@@ -90,12 +122,14 @@ func (e *EseisClient) GetCoownershipDocuments(placeID int, folderID int, page in
 	var coownershipDocuments = make([]CoownershipDocument, len(coownershipDocumentsResponse))
 	for i, coownershipDocument := range coownershipDocumentsResponse {
 		coownershipDocuments[i] = CoownershipDocument{
-			ID:            coownershipDocument.ID,
-			UUID:          coownershipDocument.UUID,
-			DisplayName:   coownershipDocument.DisplayName,
+			DocumentRef: DocumentRef{
+				ID:          coownershipDocument.ID,
+				UUID:        coownershipDocument.UUID,
+				DisplayName: coownershipDocument.DisplayName,
+				FileURL:     coownershipDocument.FileURL,
+				UpdatedAt:   coownershipDocument.UpdatedAt,
+			},
 			DocumentsName: coownershipDocument.DocumentsName,
-			FileURL:       coownershipDocument.FileURL,
-			UpdatedAt:     coownershipDocument.UpdatedAt,
 		}
 	}
 	return coownershipDocuments, nil
@@ -125,9 +159,9 @@ type MaintenanceContract struct {
 	CompanyName string
 }
 
-func (e *EseisClient) GetMaintenanceContractCategories(placeID int) ([]MaintenanceContractCategory, error) {
+func (e *EseisClient) GetMaintenanceContractCategories(ctx context.Context, placeID int) ([]MaintenanceContractCategory, error) {
 	path := fmt.Sprintf("/v1/places/%d/maintenance_contract_categories", placeID)
-	req, err := http.NewRequest("GET", e.buildURL(path), nil)
+	req, err := e.newRequest(ctx, "GET", e.buildURL(path), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create maintenance_contract_categories request: %w", err)
 	}
@@ -135,7 +169,7 @@ func (e *EseisClient) GetMaintenanceContractCategories(placeID int) ([]Maintenan
 		return nil, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := e.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send maintenance_contract_categories request: %w", err)
 	}
@@ -198,17 +232,13 @@ type MaintenanceContractDetails struct {
 }
 
 type MaintenanceContractDocument struct {
-	ID           int
-	UUID         string
-	DisplayName  string
+	DocumentRef
 	DocumentName string
-	UpdatedAt    time.Time
-	FileURL      string
 }
 
-func (e *EseisClient) GetMaintenanceContractDetails(maintenanceContractID int) (MaintenanceContractDetails, error) {
+func (e *EseisClient) GetMaintenanceContractDetails(ctx context.Context, maintenanceContractID int) (MaintenanceContractDetails, error) {
 	path := fmt.Sprintf("/v1/maintenance_contracts/%d", maintenanceContractID)
-	req, err := http.NewRequest("GET", e.buildURL(path), nil)
+	req, err := e.newRequest(ctx, "GET", e.buildURL(path), nil)
 	if err != nil {
 		return MaintenanceContractDetails{}, fmt.Errorf("failed to create maintenance_contract request: %w", err)
 	}
@@ -216,7 +246,7 @@ func (e *EseisClient) GetMaintenanceContractDetails(maintenanceContractID int) (
 		return MaintenanceContractDetails{}, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := e.do(req)
 	if err != nil {
 		return MaintenanceContractDetails{}, fmt.Errorf("failed to send maintenance_contract request: %w", err)
 	}
@@ -231,12 +261,14 @@ func (e *EseisClient) GetMaintenanceContractDetails(maintenanceContractID int) (
 	maintenanceContractDocuments := make([]MaintenanceContractDocument, len(response.MaintenanceContractDocuments))
 	for j, maintenanceContractDocument := range response.MaintenanceContractDocuments {
 		maintenanceContractDocuments[j] = MaintenanceContractDocument{
-			ID:           maintenanceContractDocument.ID,
-			UUID:         maintenanceContractDocument.UUID,
-			DisplayName:  maintenanceContractDocument.DisplayName,
+			DocumentRef: DocumentRef{
+				ID:          maintenanceContractDocument.ID,
+				UUID:        maintenanceContractDocument.UUID,
+				DisplayName: maintenanceContractDocument.DisplayName,
+				FileURL:     maintenanceContractDocument.FileURL,
+				UpdatedAt:   maintenanceContractDocument.UpdatedAt,
+			},
 			DocumentName: maintenanceContractDocument.DocumentName,
-			UpdatedAt:    maintenanceContractDocument.UpdatedAt,
-			FileURL:      maintenanceContractDocument.FileURL,
 		}
 	}
 	maintenanceContractDetails := MaintenanceContractDetails{
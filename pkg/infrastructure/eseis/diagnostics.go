@@ -0,0 +1,155 @@
+package eseis
+
+import (
+	"context"
+	"fmt"
+	cdplog "github.com/chromedp/cdproto/log"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/chrome"
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/utils"
+	"github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxConsoleEntries is the number of most recent console messages kept for the diagnostics
+	maxConsoleEntries = 500
+	// diagnosticsTimeout bounds the capture of the diagnostics of a failed page
+	diagnosticsTimeout = 15 * time.Second
+)
+
+// clearPasswordsScript empties the password fields so that the password never ends up in the diagnostics
+const clearPasswordsScript = `document.querySelectorAll("input[type=password]").forEach(el => {
+	el.value = "";
+	el.removeAttribute("value");
+})`
+
+// consoleRecorder keeps the most recent console messages, javascript exceptions and browser logs of the tab
+type consoleRecorder struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func newConsoleRecorder() *consoleRecorder {
+	return &consoleRecorder{}
+}
+
+// onEvent records the console events, it is called with every event of the tab
+func (r *consoleRecorder) onEvent(ev interface{}) {
+	var entry string
+	switch ev := ev.(type) {
+	case *runtime.EventConsoleAPICalled:
+		args := make([]string, len(ev.Args))
+		for i, arg := range ev.Args {
+			args[i] = remoteObjectString(arg)
+		}
+		entry = fmt.Sprintf("console.%s: %s", ev.Type, strings.Join(args, " "))
+	case *runtime.EventExceptionThrown:
+		details := ev.ExceptionDetails
+		entry = fmt.Sprintf("exception: %s", details.Text)
+		if details.Exception != nil && details.Exception.Description != "" {
+			entry += " " + details.Exception.Description
+		}
+	case *cdplog.EventEntryAdded:
+		entry = fmt.Sprintf("log.%s: %s", ev.Entry.Level, ev.Entry.Text)
+		if ev.Entry.URL != "" {
+			entry += " (" + ev.Entry.URL + ")"
+		}
+	default:
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, time.Now().Format(time.RFC3339Nano)+" "+entry)
+	if len(r.entries) > maxConsoleEntries {
+		r.entries = r.entries[len(r.entries)-maxConsoleEntries:]
+	}
+}
+
+// String returns the recorded messages, one per line
+func (r *consoleRecorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.entries) == 0 {
+		return ""
+	}
+	return strings.Join(r.entries, "\n") + "\n"
+}
+
+func remoteObjectString(arg *runtime.RemoteObject) string {
+	switch {
+	case len(arg.Value) > 0:
+		return string(arg.Value)
+	case arg.UnserializableValue != "":
+		return string(arg.UnserializableValue)
+	case arg.Description != "":
+		return arg.Description
+	}
+	return string(arg.Type)
+}
+
+// diagnosticsDir returns the configured diagnostics dir, defaulting to a dir under the user cache dir
+func (e *EseisClient) diagnosticsDir() (string, error) {
+	if e.config.DiagnosticsDir != "" {
+		return e.config.DiagnosticsDir, nil
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find user cache dir: %w", err)
+	}
+	return filepath.Join(cacheDir, "eseis-scrapper", "diagnostics"), nil
+}
+
+// saveLoginDiagnostics saves a screenshot, the html and the console logs of the page after a login failure.
+// It returns the url of the page and the dir of the diagnostics, each empty if unknown.
+func (e *EseisClient) saveLoginDiagnostics(ctx context.Context, c *chrome.Chrome, console *consoleRecorder, loginErr error) (pageURL string, dir string) {
+	baseDir, err := e.diagnosticsDir()
+	if err != nil {
+		logrus.Errorf("failed to save login diagnostics: %s", err)
+		return "", ""
+	}
+	dir = filepath.Join(baseDir, "login_"+time.Now().Format("20060102_150405"))
+	if err = utils.MkDir(dir); err != nil {
+		logrus.Errorf("failed to save login diagnostics: %s", err)
+		return "", ""
+	}
+
+	captureCtx, cancel := context.WithTimeout(ctx, diagnosticsTimeout)
+	defer cancel()
+	var screenshot []byte
+	var html string
+	// capture what can be captured, a broken page may fail some of the actions
+	for _, action := range []chromedp.Action{
+		chromedp.Evaluate(clearPasswordsScript, nil),
+		chromedp.Location(&pageURL),
+		chromedp.FullScreenshot(&screenshot, 100),
+		chromedp.OuterHTML("html", &html, chromedp.ByQuery),
+	} {
+		if err := c.RunTasks(captureCtx, chromedp.Tasks{action}); err != nil {
+			logrus.Warnf("failed to capture login diagnostics: %s", err)
+		}
+	}
+
+	files := map[string][]byte{
+		"error.txt":   []byte(loginErr.Error() + "\n"),
+		"console.log": []byte(console.String()),
+	}
+	if len(screenshot) > 0 {
+		files["screenshot.png"] = screenshot
+	}
+	if html != "" {
+		files["page.html"] = []byte(html)
+	}
+	for name, content := range files {
+		if err := utils.WriteBytesAtomic(filepath.Join(dir, name), content, 0600); err != nil {
+			logrus.Errorf("failed to save login diagnostics: %s", err)
+		}
+	}
+	logrus.Errorf("web UI login failed, diagnostics saved to %s", dir)
+	return pageURL, dir
+}
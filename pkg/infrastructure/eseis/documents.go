@@ -2,56 +2,183 @@ package eseis
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 )
 
-func (e *EseisClient) GetDocument(uuid string) ([]byte, error) {
-	path := fmt.Sprintf("/v1/sergic_documents?access_token=%s&uuid=%s", e.accessToken.accessToken, uuid)
-	req, err := http.NewRequest("GET", e.buildURL(path), nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create sergic_documents request: %w", err)
+// Download is a streamed file download, Body must be closed by the caller
+type Download struct {
+	Body io.ReadCloser
+	// ContentLength is the size of the file, -1 if unknown
+	ContentLength int64
+	ContentType   string
+}
+
+// GetDocument downloads the whole document in memory, prefer DownloadDocument or OpenDocument for large files
+func (e *EseisClient) GetDocument(ctx context.Context, uuid string) ([]byte, error) {
+	buffer := bytes.Buffer{}
+	if _, err := e.DownloadDocument(ctx, uuid, &buffer); err != nil {
+		return nil, err
 	}
-	if err = e.setAuthentication(req); err != nil {
+	return buffer.Bytes(), nil
+}
+
+// OpenDocument starts the download of a document and returns its streamed body
+func (e *EseisClient) OpenDocument(ctx context.Context, uuid string) (*Download, error) {
+	req, err := e.newDocumentRequest(ctx, uuid)
+	if err != nil {
 		return nil, err
 	}
+	return e.openFile(req, e.limiter.documents, "sergic_documents")
+}
 
-	resp, err := http.DefaultClient.Do(req)
+// DownloadDocument streams a document to w and returns the number of bytes written
+func (e *EseisClient) DownloadDocument(ctx context.Context, uuid string, w io.Writer) (int64, error) {
+	req, err := e.newDocumentRequest(ctx, uuid)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send sergic_documents request: %w", err)
+		return 0, err
 	}
-	defer resp.Body.Close()
+	return e.downloadFile(req, e.limiter.documents, "sergic_documents", w)
+}
 
+// GetAttachment downloads the whole attachment in memory, prefer DownloadAttachment or OpenAttachment for large files
+func (e *EseisClient) GetAttachment(ctx context.Context, url string) ([]byte, error) {
 	buffer := bytes.Buffer{}
-	_, err = io.Copy(&buffer, resp.Body)
+	if _, err := e.DownloadAttachment(ctx, url, &buffer); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// OpenAttachment starts the download of an attachment and returns its streamed body
+func (e *EseisClient) OpenAttachment(ctx context.Context, url string) (*Download, error) {
+	req, err := e.newAttachmentRequest(ctx, url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read sergic_documents response body: %w", err)
+		return nil, err
 	}
+	return e.openFile(req, e.limiter.attachments, "attachments")
+}
 
-	return buffer.Bytes(), nil
+// DownloadAttachment streams an attachment to w and returns the number of bytes written
+func (e *EseisClient) DownloadAttachment(ctx context.Context, url string, w io.Writer) (int64, error) {
+	req, err := e.newAttachmentRequest(ctx, url)
+	if err != nil {
+		return 0, err
+	}
+	return e.downloadFile(req, e.limiter.attachments, "attachments", w)
 }
 
-func (e *EseisClient) GetAttachment(url string) ([]byte, error) {
-	req, err := http.NewRequest("GET", url, nil)
+// DocumentURL returns the url of the document download, without the access token
+func (e *EseisClient) DocumentURL(uuid string) string {
+	return e.buildURL(fmt.Sprintf("/v1/sergic_documents?uuid=%s", uuid))
+}
+
+func (e *EseisClient) newDocumentRequest(ctx context.Context, uuid string) (*http.Request, error) {
+	// the access token query parameter is set by setAuthentication, along with the header
+	path := fmt.Sprintf("/v1/sergic_documents?access_token=&uuid=%s", uuid)
+	req, err := e.newRequest(ctx, "GET", e.buildURL(path), nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create attachments request: %w", err)
+		return nil, fmt.Errorf("failed to create sergic_documents request: %w", err)
 	}
 	if err = e.setAuthentication(req); err != nil {
 		return nil, err
 	}
+	return req, nil
+}
 
-	resp, err := http.DefaultClient.Do(req)
+func (e *EseisClient) newAttachmentRequest(ctx context.Context, url string) (*http.Request, error) {
+	req, err := e.newRequest(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send attachments request: %w", err)
+		return nil, fmt.Errorf("failed to create attachments request: %w", err)
+	}
+	if err = e.setAuthentication(req); err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return req, nil
+}
 
-	buffer := bytes.Buffer{}
-	_, err = io.Copy(&buffer, resp.Body)
+func (e *EseisClient) openFile(req *http.Request, bucket *tokenBucket, name string) (*Download, error) {
+	resp, err := e.doLimited(req, bucket)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read attachments response body: %w", err)
+		return nil, fmt.Errorf("failed to send %s request: %w", name, err)
+	}
+	if err = checkFileResponse(req, resp); err != nil {
+		resp.Body.Close()
+		return nil, err
 	}
+	return &Download{
+		Body:          resp.Body,
+		ContentLength: resp.ContentLength,
+		ContentType:   resp.Header.Get("Content-Type"),
+	}, nil
+}
 
-	return buffer.Bytes(), nil
+// downloadFile streams the response body to w, retrying the whole download so that a connection reset while
+// reading the body does not fail it. Once bytes were written, retries are only possible if w can be rewound.
+func (e *EseisClient) downloadFile(req *http.Request, bucket *tokenBucket, name string, w io.Writer) (int64, error) {
+	rewind := rewindFunc(w)
+	var written int64
+	var lastErr error
+	err := e.withRetry(req.Context(), name+" download", func() error {
+		if written > 0 {
+			if rewind == nil {
+				return fmt.Errorf("%w: %s", errPartialDownload, lastErr)
+			}
+			if err := rewind(); err != nil {
+				return fmt.Errorf("failed to rewind %s download: %w", name, err)
+			}
+			written = 0
+		}
+		if err := bucket.wait(req.Context()); err != nil {
+			return err
+		}
+		resp, err := e.send(req)
+		if err != nil {
+			return fmt.Errorf("failed to send %s request: %w", name, err)
+		}
+		defer resp.Body.Close()
+		if err = checkFileResponse(req, resp); err != nil {
+			return err
+		}
+		written, err = io.Copy(w, resp.Body)
+		if err == nil && resp.ContentLength >= 0 && written != resp.ContentLength {
+			err = fmt.Errorf("%w: got %d bytes out of %d", io.ErrUnexpectedEOF, written, resp.ContentLength)
+		}
+		if err != nil {
+			lastErr = fmt.Errorf("failed to read %s response body: %w", name, err)
+			return lastErr
+		}
+		return nil
+	})
+	return written, err
+}
+
+// rewindFunc returns a function restoring w to its current position, or nil if w cannot be rewound
+func rewindFunc(w io.Writer) func() error {
+	switch w := w.(type) {
+	case *bytes.Buffer:
+		length := w.Len()
+		return func() error {
+			w.Truncate(length)
+			return nil
+		}
+	case interface {
+		io.Seeker
+		Truncate(size int64) error
+	}:
+		offset, err := w.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil
+		}
+		return func() error {
+			if err := w.Truncate(offset); err != nil {
+				return err
+			}
+			_, err := w.Seek(offset, io.SeekStart)
+			return err
+		}
+	}
+	return nil
 }
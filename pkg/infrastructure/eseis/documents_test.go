@@ -0,0 +1,138 @@
+package eseis
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writerOnly hides the rewind methods of the underlying writer
+type writerOnly struct {
+	io.Writer
+}
+
+// newDownloadTestClient returns a client whose document downloads are answered by bodies, one per attempt.
+// Each body is sent with the Content-Length of the whole document.
+func newDownloadTestClient(t *testing.T, document string, bodies ...string) (*EseisClient, *int) {
+	t.Helper()
+	client := newTestClient(t, http.NotFoundHandler())
+	client.config.Retry = RetryPolicy{MaxAttempts: len(bodies), BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	client.accessToken = &authToken{accessToken: "token", expiresAt: time.Now().Add(time.Hour)}
+	attempts := 0
+	client.httpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := bodies[attempts]
+		attempts++
+		return &http.Response{
+			StatusCode:    http.StatusOK,
+			Header:        http.Header{"Content-Type": []string{"application/pdf"}},
+			Body:          io.NopCloser(strings.NewReader(body)),
+			ContentLength: int64(len(document)),
+			Request:       req,
+		}, nil
+	})}
+	return client, &attempts
+}
+
+func TestDownloadFileRewindsOnRetry(t *testing.T) {
+	const document = "whole document"
+	tests := []struct {
+		name    string
+		writer  func(t *testing.T) (io.Writer, func() string)
+		prefix  string
+		wantErr error
+	}{
+		{"buffer", func(t *testing.T) (io.Writer, func() string) {
+			buffer := bytes.NewBufferString("prefix ")
+			return buffer, buffer.String
+		}, "prefix ", nil},
+		{"file", func(t *testing.T) (io.Writer, func() string) {
+			file, err := os.Create(filepath.Join(t.TempDir(), "document.pdf"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { file.Close() })
+			if _, err = io.WriteString(file, "prefix "); err != nil {
+				t.Fatal(err)
+			}
+			return file, func() string {
+				data, err := os.ReadFile(file.Name())
+				if err != nil {
+					t.Fatal(err)
+				}
+				return string(data)
+			}
+		}, "prefix ", nil},
+		{"not rewindable", func(t *testing.T) (io.Writer, func() string) {
+			buffer := &bytes.Buffer{}
+			return writerOnly{buffer}, buffer.String
+		}, "", errPartialDownload},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, attempts := newDownloadTestClient(t, document, "whole", document)
+			w, content := test.writer(t)
+
+			written, err := client.DownloadDocument(context.Background(), "uuid", w)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+			if test.wantErr != nil {
+				if *attempts != 1 {
+					t.Errorf("got %d attempts, want no retry once bytes were written", *attempts)
+				}
+				return
+			}
+			if written != int64(len(document)) {
+				t.Errorf("got %d bytes written, want %d", written, len(document))
+			}
+			if got := content(); got != test.prefix+document {
+				t.Errorf("got content %q, want the partial attempt rewound to %q", got, test.prefix+document)
+			}
+		})
+	}
+}
+
+func TestDownloadFileFailsOnContentLengthMismatch(t *testing.T) {
+	client, attempts := newDownloadTestClient(t, "whole document", "whole", "whole doc")
+
+	var buffer bytes.Buffer
+	_, err := client.DownloadDocument(context.Background(), "uuid", &buffer)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("got error %v, want io.ErrUnexpectedEOF", err)
+	}
+	if *attempts != 2 {
+		t.Errorf("got %d attempts, want the short bodies retried until the policy is exhausted", *attempts)
+	}
+}
+
+func TestOpenDocumentWaitsForTheEndpointLimitOnEachAttempt(t *testing.T) {
+	attempts := 0
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = io.WriteString(w, "document")
+	}))
+	client.config.Retry = RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	client.accessToken = &authToken{accessToken: "token", expiresAt: time.Now().Add(time.Hour)}
+	client.limiter.documents = newTokenBucket("documents", 1000, 1)
+
+	download, err := client.OpenDocument(context.Background(), "uuid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	download.Body.Close()
+	if got := client.limiter.documents.stats().Requests; got != 2 {
+		t.Errorf("got %d requests through the documents limiter, want one per attempt", got)
+	}
+}
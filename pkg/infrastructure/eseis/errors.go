@@ -0,0 +1,172 @@
+package eseis
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxErrorBodySize is the maximum number of bytes of an error response body kept in an APIError
+const maxErrorBodySize = 512
+
+var (
+	// ErrUnauthorized matches API errors with a 401 or 403 status code
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrNotFound matches API errors with a 404 status code
+	ErrNotFound = errors.New("not found")
+	// ErrRateLimited matches API errors with a 429 status code, see APIError.RetryAfter
+	ErrRateLimited = errors.New("rate limited")
+	// ErrUnexpectedContentType is returned when a file download answers with an html page instead of a file
+	ErrUnexpectedContentType = errors.New("unexpected content type")
+
+	// errPartialDownload is returned when a download failed after writing to a writer which cannot be rewound
+	errPartialDownload = errors.New("download failed after a partial write")
+)
+
+// APIError is returned when the Eseis API answers with a non 2xx status code.
+// Use errors.Is with ErrUnauthorized, ErrNotFound or ErrRateLimited to check for well known statuses.
+type APIError struct {
+	StatusCode int
+	URL        string
+	// Body is the beginning of the response body
+	Body string
+	// RetryAfter is the delay requested by the Retry-After header, zero if absent
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("eseis api answered %d %s for %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.URL, e.Body)
+}
+
+// Is makes errors.Is match the sentinel error corresponding to the status code
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// checkResponse returns an *APIError if the response to req has a non 2xx status code.
+// req is passed explicitly, custom transports may leave resp.Request nil.
+func checkResponse(req *http.Request, resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		URL:        redactURL(req.URL),
+		Body:       strings.TrimSpace(string(body)),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// checkFileResponse checks the response to req is a file and not an html page
+func checkFileResponse(req *http.Request, resp *http.Response) error {
+	contentType := resp.Header.Get("Content-Type")
+	if strings.HasPrefix(contentType, "text/html") {
+		return fmt.Errorf("%w %s for %s", ErrUnexpectedContentType, contentType, redactURL(req.URL))
+	}
+	return nil
+}
+
+// redactURL hides the credentials and the access token query parameter of the url
+func redactURL(u *url.URL) string {
+	redacted := *u
+	query := redacted.Query()
+	if query.Has("access_token") {
+		query.Set("access_token", "xxxxx")
+		redacted.RawQuery = query.Encode()
+	}
+	return redacted.Redacted()
+}
+
+// parseRetryAfter parses a Retry-After header value given either in seconds or as an http date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
+// do sends the request, retrying it if it is idempotent, and returns an error if the response status code is not 2xx.
+// The response body must be closed by the caller when no error is returned.
+func (e *EseisClient) do(req *http.Request) (*http.Response, error) {
+	return e.doLimited(req, nil)
+}
+
+// doLimited is do for an endpoint with its own rate limit, every attempt waits for a token of bucket
+func (e *EseisClient) doLimited(req *http.Request, bucket *tokenBucket) (*http.Response, error) {
+	if !isIdempotent(req) {
+		if err := bucket.wait(req.Context()); err != nil {
+			return nil, err
+		}
+		return e.send(req)
+	}
+	var resp *http.Response
+	err := e.withRetry(req.Context(), req.Method+" "+req.URL.Path, func() error {
+		if err := bucket.wait(req.Context()); err != nil {
+			return err
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return err
+			}
+			req.Body = body
+		}
+		var err error
+		resp, err = e.send(req)
+		return err
+	})
+	return resp, err
+}
+
+// send sends the request and returns an error if the response status code is not 2xx.
+// A request rejected with a 401 is sent again once with a renewed token, the token may have been revoked before expiry.
+// The response body must be closed by the caller when no error is returned.
+func (e *EseisClient) send(req *http.Request) (*http.Response, error) {
+	resp, err := e.sendOnce(req)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if !e.reauthenticate(req) {
+		return nil, err
+	}
+	return e.sendOnce(req)
+}
+
+// sendOnce sends the request once and returns an error if the response status code is not 2xx
+func (e *EseisClient) sendOnce(req *http.Request) (*http.Response, error) {
+	if err := e.limiter.global.wait(req.Context()); err != nil {
+		return nil, err
+	}
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse(req, resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
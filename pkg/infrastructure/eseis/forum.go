@@ -1,46 +1,29 @@
 package eseis
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/idkw/eseisscrapper/pkg/infrastructure/utils"
-	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
 
 type forumTopicsResponse struct {
-	ID          int       `json:"id"`
-	UUID        string    `json:"uuid"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	DisplayName string    `json:"display_name"`
-	State       string    `json:"state"`
-	Read        bool      `json:"read"`
-	PostCount   int       `json:"post_count"`
-	Description string    `json:"description"`
-	Author      struct {
-		ID               int         `json:"id"`
-		TopPlaceRole     string      `json:"top_place_role"`
-		InvitedBy        interface{} `json:"invited_by"`
-		RealName         string      `json:"real_name"`
-		IdentityID       int         `json:"identity_id"`
-		DisplayPlaceRole string      `json:"display_place_role"`
-		DisplayName      string      `json:"display_name"`
-		AvatarURL        string      `json:"avatar_url"`
-		SergicPartner    bool        `json:"sergic_partner"`
-		CanTalk          bool        `json:"can_talk"`
-		Contracts        []struct {
-			ID                      int    `json:"id"`
-			DisplayName             string `json:"display_name"`
-			PendingAmount           int    `json:"pending_amount"`
-			CustomerReferenceNumber string `json:"customer_reference_number"`
-		} `json:"contracts"`
-	} `json:"author"`
-	EditedAt            interface{} `json:"edited_at"`
-	DisplayCategoryKind string      `json:"display_category_kind"`
-	PlaceDisplayName    string      `json:"place_display_name"`
+	ID                  int            `json:"id"`
+	UUID                string         `json:"uuid"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+	DisplayName         string         `json:"display_name"`
+	State               string         `json:"state"`
+	Read                bool           `json:"read"`
+	PostCount           int            `json:"post_count"`
+	Description         string         `json:"description"`
+	Author              authorResponse `json:"author"`
+	EditedAt            interface{}    `json:"edited_at"`
+	DisplayCategoryKind string         `json:"display_category_kind"`
+	PlaceDisplayName    string         `json:"place_display_name"`
 	Category            struct {
 		ID              int       `json:"id"`
 		Kind            string    `json:"kind"`
@@ -52,34 +35,57 @@ type forumTopicsResponse struct {
 		OpenTopicsCount int       `json:"open_topics_count"`
 		TopicsCount     int       `json:"topics_count"`
 	} `json:"category"`
-	Attachments []struct {
-		ID                int         `json:"id"`
-		UUID              string      `json:"uuid"`
-		SourceFileName    string      `json:"source_file_name"`
-		SourceContentType string      `json:"source_content_type"`
-		SourceFileSize    int         `json:"source_file_size"`
-		FileURL           string      `json:"file_url"`
-		Dimensions        interface{} `json:"dimensions"`
-		SourceUpdatedAt   time.Time   `json:"source_updated_at"`
-		DisplayName       interface{} `json:"display_name"`
-	} `json:"attachments"`
+	Attachments []attachmentResponse `json:"attachments"`
 }
 
 func (f ForumTopic) CleanDisplayName() string {
 	return strings.Trim(strings.ReplaceAll(f.DisplayName, "/", "_"), "")
 }
 
+// FileBaseName returns the name, without extension, of the files and dir exported for the forum topic
+func (f ForumTopic) FileBaseName() string {
+	year, month, day := f.CreatedAt.Date()
+	return fmt.Sprintf("%d_%d_%d__%d__%s", year, month, day, f.ID, f.CleanDisplayName())
+}
+
 type ForumTopic struct {
 	ID          int
 	UUID        string
 	DisplayName string
-	CreatedAt   time.Time `json:"created_at"`
-	Raw         *forumTopicsResponse
+	Description string
+	State       string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Author      Author
+	Attachments []Attachment
+	// URL is the address of the topic in the web UI
+	URL string
+}
+
+// defaultForumTopicsPerPage is the number of items per page requested by GetForumTopics
+const defaultForumTopicsPerPage = 20
+
+// GetForumTopics returns a page of the forum topics of a place, pages start at 1. See IterForumTopics to go through all of them.
+func (e *EseisClient) GetForumTopics(ctx context.Context, placeID int, page int) ([]ForumTopic, error) {
+	return e.getForumTopicsPage(ctx, placeID, page, defaultForumTopicsPerPage)
+}
+
+// IterForumTopics returns a Pager over all the forum topics of a place
+func (e *EseisClient) IterForumTopics(placeID int, opts ...PagerOption) *Pager[ForumTopic] {
+	fetch := func(ctx context.Context, page int, perPage int) ([]ForumTopic, error) {
+		return e.getForumTopicsPage(ctx, placeID, page, perPage)
+	}
+	return NewPager(fetch, append([]PagerOption{WithPerPage(defaultForumTopicsPerPage)}, opts...)...)
 }
 
-func (e *EseisClient) GetForumTopics(placeID int, page int) ([]ForumTopic, error) {
-	path := fmt.Sprintf("/v1/places/%d/forum/topics?page=%d&per_page=20&sort=-updated_at", placeID, page)
-	req, err := http.NewRequest("GET", e.buildURL(path), nil)
+// AllForumTopics returns all the forum topics of a place
+func (e *EseisClient) AllForumTopics(ctx context.Context, placeID int, opts ...PagerOption) ([]ForumTopic, error) {
+	return e.IterForumTopics(placeID, opts...).All(ctx)
+}
+
+func (e *EseisClient) getForumTopicsPage(ctx context.Context, placeID int, page int, perPage int) ([]ForumTopic, error) {
+	path := fmt.Sprintf("/v1/places/%d/forum/topics?%s&sort=-updated_at", placeID, pageQuery(page, perPage))
+	req, err := e.newRequest(ctx, "GET", e.buildURL(path), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create forum topics request: %w", err)
 	}
@@ -87,7 +93,7 @@ func (e *EseisClient) GetForumTopics(placeID int, page int) ([]ForumTopic, error
 		return nil, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := e.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send forum topics request: %w", err)
 	}
@@ -105,90 +111,104 @@ func (e *EseisClient) GetForumTopics(placeID int, page int) ([]ForumTopic, error
 			ID:          r.ID,
 			UUID:        r.UUID,
 			DisplayName: r.DisplayName,
+			Description: r.Description,
+			State:       r.State,
 			CreatedAt:   r.CreatedAt,
-			Raw:         &r,
+			UpdatedAt:   r.UpdatedAt,
+			Author:      r.Author.toAuthor(),
+			Attachments: toAttachments(r.Attachments),
+			URL:         e.buildWebURL(fmt.Sprintf("/mes-echanges/forum/%d", r.ID)),
 		}
 	}
 	return forumTopics, nil
 }
 
-func (e *EseisClient) CreateForumTopicScreenshot(forumTopic ForumTopic, outDir string) error {
-	forumTopicName := forumTopic.CleanDisplayName()
-	year, month, day := forumTopic.CreatedAt.Date()
-	forumTopicFileName := fmt.Sprintf("%d_%d_%d__%d__%s.pdf", year, month, day, forumTopic.ID, forumTopicName)
-	forumTopicPath := filepath.Join(outDir, forumTopicFileName)
-	url := fmt.Sprintf("https://client.eseis-syndic.com/mes-echanges/forum/%d", forumTopic.ID)
-	return e.SavePDF(url, forumTopicPath, WaitForForumPageActions()...)
-}
-
-type postResponse struct {
-	ID        int       `json:"id"`
-	Body      string    `json:"body"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Author    struct {
-		ID               int           `json:"id"`
-		TopPlaceRole     string        `json:"top_place_role"`
-		InvitedBy        interface{}   `json:"invited_by"`
-		RealName         interface{}   `json:"real_name"`
-		IdentityID       int           `json:"identity_id"`
-		DisplayPlaceRole string        `json:"display_place_role"`
-		DisplayName      string        `json:"display_name"`
-		AvatarURL        string        `json:"avatar_url"`
-		SergicPartner    bool          `json:"sergic_partner"`
-		CanTalk          bool          `json:"can_talk"`
-		Contracts        []interface{} `json:"contracts"`
-	} `json:"author"`
-	Attachments []struct {
-		ID                int         `json:"id"`
-		UUID              string      `json:"uuid"`
-		SourceFileName    string      `json:"source_file_name"`
-		SourceContentType string      `json:"source_content_type"`
-		SourceFileSize    int         `json:"source_file_size"`
-		FileURL           string      `json:"file_url"`
-		Dimensions        interface{} `json:"dimensions"`
-		SourceUpdatedAt   time.Time   `json:"source_updated_at"`
-		DisplayName       interface{} `json:"display_name"`
-	} `json:"attachments"`
-}
-
-func (e *EseisClient) GetAllTopicPosts(placeID int, topicID int) ([]postResponse, error) {
-	allPosts := make([]postResponse, 0)
-	page := 1
-	for {
-		posts, err := e.GetTopicPosts(placeID, topicID, page)
-		utils.MustBeNilErr(err, "failed to get topic posts for placeID=%d topic=%d page=%d", placeID, topicID, page)
-		if len(posts) == 0 {
-			break
-		}
-		allPosts = append(allPosts, posts...)
+// CreateForumTopicScreenshot captures the forum topic page to outDir in each configured capture format
+func (e *EseisClient) CreateForumTopicScreenshot(ctx context.Context, forumTopic ForumTopic, outDir string) error {
+	forumTopicPath := filepath.Join(outDir, forumTopic.FileBaseName())
+	return e.Capture(ctx, forumTopic.URL, strconv.Itoa(forumTopic.ID), forumTopicPath, e.config.CaptureFormats, e.forumPageActions()...)
+}
 
-		page++
+type topicPostResponse struct {
+	ID          int                  `json:"id"`
+	Body        string               `json:"body"`
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+	Author      authorResponse       `json:"author"`
+	Attachments []attachmentResponse `json:"attachments"`
+}
+
+// TopicPost is a post of a forum topic
+type TopicPost struct {
+	ID          int
+	Body        string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Author      Author
+	Attachments []Attachment
+}
+
+// GetAllTopicPosts returns all the posts of a forum topic
+//
+// Deprecated: use AllTopicPosts
+func (e *EseisClient) GetAllTopicPosts(ctx context.Context, placeID int, topicID int) ([]TopicPost, error) {
+	return e.AllTopicPosts(ctx, placeID, topicID)
+}
+
+// defaultTopicPostsPerPage is the number of items per page requested by GetTopicPosts
+const defaultTopicPostsPerPage = 15
+
+// GetTopicPosts returns a page of the posts of a forum topic, pages start at 1. See IterTopicPosts to go through all of them.
+func (e *EseisClient) GetTopicPosts(ctx context.Context, placeID int, topicID int, page int) ([]TopicPost, error) {
+	return e.getTopicPostsPage(ctx, placeID, topicID, page, defaultTopicPostsPerPage)
+}
+
+// IterTopicPosts returns a Pager over all the posts of a forum topic
+func (e *EseisClient) IterTopicPosts(placeID int, topicID int, opts ...PagerOption) *Pager[TopicPost] {
+	fetch := func(ctx context.Context, page int, perPage int) ([]TopicPost, error) {
+		return e.getTopicPostsPage(ctx, placeID, topicID, page, perPage)
 	}
-	return allPosts, nil
+	return NewPager(fetch, append([]PagerOption{WithPerPage(defaultTopicPostsPerPage)}, opts...)...)
 }
 
-func (e *EseisClient) GetTopicPosts(placeID int, topicID int, page int) ([]postResponse, error) {
-	path := fmt.Sprintf("/v1/forum/topics/%d/posts?page=%d&per_page=15&sort=-updated_at", topicID, page)
-	req, err := http.NewRequest("GET", e.buildURL(path), nil)
-	req.Header.Set("x-current-place-id", fmt.Sprintf("%d", placeID))
+// AllTopicPosts returns all the posts of a forum topic
+func (e *EseisClient) AllTopicPosts(ctx context.Context, placeID int, topicID int, opts ...PagerOption) ([]TopicPost, error) {
+	return e.IterTopicPosts(placeID, topicID, opts...).All(ctx)
+}
+
+func (e *EseisClient) getTopicPostsPage(ctx context.Context, placeID int, topicID int, page int, perPage int) ([]TopicPost, error) {
+	path := fmt.Sprintf("/v1/forum/topics/%d/posts?%s&sort=-updated_at", topicID, pageQuery(page, perPage))
+	req, err := e.newRequest(ctx, "GET", e.buildURL(path), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create topic posts request: %w", err)
 	}
+	req.Header.Set("x-current-place-id", fmt.Sprintf("%d", placeID))
 	if err = e.setAuthentication(req); err != nil {
 		return nil, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := e.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send topic posts request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	var response []postResponse
+	var response []topicPostResponse
 	err = json.NewDecoder(resp.Body).Decode(&response)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode topic posts response: %w", err)
 	}
-	return response, nil
+
+	posts := make([]TopicPost, len(response))
+	for i, post := range response {
+		posts[i] = TopicPost{
+			ID:          post.ID,
+			Body:        post.Body,
+			CreatedAt:   post.CreatedAt,
+			UpdatedAt:   post.UpdatedAt,
+			Author:      post.Author.toAuthor(),
+			Attachments: toAttachments(post.Attachments),
+		}
+	}
+	return posts, nil
 }
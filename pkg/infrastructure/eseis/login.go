@@ -0,0 +1,183 @@
+package eseis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/kb"
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/chrome"
+	"github.com/sirupsen/logrus"
+	"strings"
+)
+
+var (
+	// ErrLoginPageChanged matches login errors where an expected element of the login page was not found,
+	// the selector profile must be updated
+	ErrLoginPageChanged = errors.New("login page changed")
+	// ErrWebUIUnavailable matches login errors where the web UI showed a maintenance or an error page
+	ErrWebUIUnavailable = errors.New("web UI unavailable")
+)
+
+// LoginError is returned when the browser fails to log in to the web UI.
+// Use errors.Is with ErrInvalidCredentials, ErrLoginPageChanged or ErrWebUIUnavailable to check for known causes.
+type LoginError struct {
+	// Step is the login step which failed
+	Step string
+	// URL is the address of the page when the login failed, empty if unknown
+	URL string
+	// Reason is ErrInvalidCredentials, ErrLoginPageChanged, ErrWebUIUnavailable or nil if the cause is unknown
+	Reason error
+	// Err is the browser error, nil if the failure was detected by inspecting the page
+	Err error
+	// DiagnosticsDir holds the screenshot, html and console logs of the page, empty if they could not be saved
+	DiagnosticsDir string
+}
+
+func (e *LoginError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "web UI login failed to %s", e.Step)
+	if e.URL != "" {
+		fmt.Fprintf(&b, " on %s", e.URL)
+	}
+	if e.Reason != nil {
+		fmt.Fprintf(&b, ": %s", e.Reason)
+	}
+	if e.Err != nil {
+		fmt.Fprintf(&b, ": %s", e.Err)
+	}
+	if e.DiagnosticsDir != "" {
+		fmt.Fprintf(&b, " (diagnostics saved to %s)", e.DiagnosticsDir)
+	}
+	return b.String()
+}
+
+// Is makes errors.Is match the reason of the failure
+func (e *LoginError) Is(target error) bool {
+	return e.Reason != nil && target == e.Reason
+}
+
+func (e *LoginError) Unwrap() error {
+	return e.Err
+}
+
+// loginState is the state of the login page detected by page inspection
+type loginState int
+
+const (
+	loginStateTimedOut loginState = iota
+	loginStateReady
+	loginStateInvalidCredentials
+	loginStateUnavailable
+	loginStateLoginForm
+)
+
+// loginCheck is a state of the login page detected when one of its selectors is visible
+type loginCheck struct {
+	selectors []Selector
+	state     loginState
+}
+
+// loginChecks returns the checks of a login step waiting for target, in order of precedence
+func (e *EseisClient) loginChecks(target Selector) []loginCheck {
+	return []loginCheck{
+		{[]Selector{target}, loginStateReady},
+		{e.selectors.Login.InvalidCredentials, loginStateInvalidCredentials},
+		{e.selectors.Login.Unavailable, loginStateUnavailable},
+	}
+}
+
+// login logs in to the web UI by typing the credentials in the login form.
+// On failure the state of the page is saved to the diagnostics dir and a *LoginError is returned.
+func (e *EseisClient) login(ctx context.Context, c *chrome.Chrome, console *consoleRecorder) error {
+	selectors := e.selectors.Login
+	steps := []struct {
+		name    string
+		actions []chromedp.Action
+		waitFor Selector
+	}{
+		{
+			name:    "open the login page",
+			actions: []chromedp.Action{chromedp.EmulateViewport(799, 799), chromedp.Navigate(e.config.BaseWebURL)},
+			waitFor: selectors.Username,
+		},
+		{
+			name:    "submit the username",
+			actions: []chromedp.Action{selectors.Username.sendKeys(e.config.Username + kb.Enter)},
+			waitFor: selectors.Password,
+		},
+		{
+			name:    "submit the password",
+			actions: []chromedp.Action{selectors.Password.sendKeys(e.config.Password + kb.Enter)},
+			waitFor: selectors.LoggedIn,
+		},
+	}
+	for _, step := range steps {
+		state := loginStateTimedOut
+		tasks := append(step.actions, e.waitLoginStateAction(e.loginChecks(step.waitFor), &state))
+		err := c.RunTasks(ctx, tasks)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == nil && state == loginStateReady {
+			continue
+		}
+		loginErr := &LoginError{Step: step.name, Err: err}
+		switch {
+		case err != nil:
+		case state == loginStateInvalidCredentials:
+			loginErr.Reason = ErrInvalidCredentials
+		case state == loginStateUnavailable:
+			loginErr.Reason = ErrWebUIUnavailable
+		default:
+			loginErr.Reason = ErrLoginPageChanged
+			loginErr.Err = fmt.Errorf("element %s not found after %s", step.waitFor, e.config.Readiness.Timeout)
+		}
+		loginErr.URL, loginErr.DiagnosticsDir = e.saveLoginDiagnostics(ctx, c, console, loginErr)
+		return loginErr
+	}
+	return nil
+}
+
+// waitLoginStateAction waits until the page matches one of the checks and sets state to its state.
+// Cookie banners are dismissed while waiting. state is left to loginStateTimedOut after the readiness timeout.
+func (e *EseisClient) waitLoginStateAction(checks []loginCheck, state *loginState) chromedp.Action {
+	selectors := e.selectors.Login
+	timeout := e.config.Readiness.Timeout
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		waitCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		err := pollUntil(waitCtx, func(ctx context.Context) (bool, error) {
+			// evaluation errors are expected while the page navigates, the next poll retries
+			for _, banner := range selectors.CookieBanner {
+				var clicked bool
+				if err := chromedp.Evaluate(banner.jsClick(), &clicked).Do(ctx); err != nil {
+					logrus.Debugf("failed to dismiss banner %s: %s", banner, err)
+					return false, nil
+				}
+				if clicked {
+					logrus.Debugf("dismissed banner %s", banner)
+				}
+			}
+			for _, check := range checks {
+				for _, selector := range check.selectors {
+					var visible bool
+					if err := chromedp.Evaluate(selector.jsIsVisible(), &visible).Do(ctx); err != nil {
+						logrus.Debugf("failed to look for %s: %s", selector, err)
+						return false, nil
+					}
+					if visible {
+						*state = check.state
+						return true, nil
+					}
+				}
+			}
+			return false, nil
+		})
+		if err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+			// timed out, the caller inspects the state
+			return nil
+		}
+		return err
+	})
+}
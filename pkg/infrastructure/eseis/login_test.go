@@ -0,0 +1,72 @@
+package eseis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// loginTestProfile returns the default selector profile with the selectors of the test login pages
+func loginTestProfile(t *testing.T) SelectorProfile {
+	profile, err := DefaultSelectorProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile.Login.CookieBanner = []Selector{{CSS: "#accept-cookies"}}
+	profile.Login.InvalidCredentials = []Selector{{Text: "Wrong password"}}
+	profile.Login.Unavailable = []Selector{{Text: "Under maintenance"}}
+	return *profile
+}
+
+func TestLoginReportsTheReasonOfTheFailure(t *testing.T) {
+	client := newBrowserTestClient(t, WithSelectorProfile(loginTestProfile(t)))
+	client.config.Readiness.Timeout = time.Second
+	client.config.DiagnosticsDir = t.TempDir()
+
+	tests := []struct {
+		name string
+		page string
+		want error
+	}{
+		{"maintenance page", `<p>Under maintenance</p>`, ErrWebUIUnavailable},
+		{"invalid credentials", `<p>Wrong password</p>`, ErrInvalidCredentials},
+		{"unknown page", `<p>Welcome</p>`, ErrLoginPageChanged},
+		{"hidden message", `<p style="display: none">Under maintenance</p>`, ErrLoginPageChanged},
+		{
+			"message behind the cookie banner",
+			`<button id="accept-cookies" onclick="document.getElementById('message').style.display = 'block'">OK</button>` +
+				`<p id="message" style="display: none">Under maintenance</p>`,
+			ErrWebUIUnavailable,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, "<html><body>%s</body></html>", test.page)
+			}))
+			defer server.Close()
+			client.config.BaseWebURL = server.URL
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			err := client.login(ctx, client.chromeSession, client.console)
+			var loginErr *LoginError
+			if !errors.As(err, &loginErr) {
+				t.Fatalf("got error %v, want a LoginError", err)
+			}
+			if loginErr.Reason != test.want || !errors.Is(err, test.want) {
+				t.Errorf("got reason %v, want %v", loginErr.Reason, test.want)
+			}
+			if loginErr.Step != "open the login page" {
+				t.Errorf("got step %q, want the login page", loginErr.Step)
+			}
+			if loginErr.DiagnosticsDir == "" {
+				t.Error("got no diagnostics")
+			}
+		})
+	}
+}
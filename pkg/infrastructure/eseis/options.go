@@ -0,0 +1,58 @@
+package eseis
+
+import (
+	"net/http"
+)
+
+// Option customizes an EseisClient built by NewEseisClient
+type Option func(*EseisClient)
+
+// WithConfig sets the configuration of the client
+func WithConfig(config Config) Option {
+	return func(e *EseisClient) {
+		e.config = &config
+	}
+}
+
+// WithHTTPClient sets the http client used to call the Eseis API
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(e *EseisClient) {
+		e.httpClient = httpClient
+	}
+}
+
+// WithTransport sets the round tripper used to call the Eseis API, e.g. to use a proxy, a custom CA or tracing.
+// It is set on a copy of the http client, keeping its timeout and cookie jar whatever the order of the options.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(e *EseisClient) {
+		e.transport = transport
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every Eseis API request
+func WithUserAgent(userAgent string) Option {
+	return func(e *EseisClient) {
+		e.userAgent = userAgent
+	}
+}
+
+// WithoutChrome disables all browser work, screenshots then fail with ErrChromeDisabled
+func WithoutChrome() Option {
+	return func(e *EseisClient) {
+		e.chromeDisabled = true
+	}
+}
+
+// WithSelectorProfile sets the selectors used to automate the web UI, overriding the default and configured ones
+func WithSelectorProfile(profile SelectorProfile) Option {
+	return func(e *EseisClient) {
+		e.selectors = &profile
+	}
+}
+
+// WithTokenStore sets the store used to persist the oauth token between runs
+func WithTokenStore(tokenStore TokenStore) Option {
+	return func(e *EseisClient) {
+		e.tokenStore = tokenStore
+	}
+}
@@ -0,0 +1,123 @@
+package eseis
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// tokenTransport answers every request with an oauth token, recording the requests in requests
+func tokenTransport(requests *[]*http.Request) roundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		*requests = append(*requests, req)
+		body := `{"access_token": "token", "expires_in": 7200, "refresh_token": "refresh", "token_type": "Bearer"}`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	}
+}
+
+func testConfig() Config {
+	return Config{ClientId: "client", Username: "user", Password: "password"}
+}
+
+func TestWithUserAgent(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		// the transport adds its own user agent to the requests without one
+		{"default", nil, ""},
+		{"custom", []Option{WithUserAgent("eseis-scrapper/test")}, "eseis-scrapper/test"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests []*http.Request
+			opts := append([]Option{WithConfig(testConfig()), WithTransport(tokenTransport(&requests))}, test.opts...)
+			client, err := NewEseisClient(opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = client.Authenticate(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if len(requests) != 1 {
+				t.Fatalf("got %d requests, want 1", len(requests))
+			}
+			if got := requests[0].Header.Get("User-Agent"); got != test.want {
+				t.Errorf("got user agent %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWithTransportKeepsTheHTTPClient(t *testing.T) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpClient := &http.Client{Timeout: 7 * time.Second, Jar: jar}
+	var requests []*http.Request
+	transport := tokenTransport(&requests)
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"transport after client", []Option{WithHTTPClient(httpClient), WithTransport(transport)}},
+		{"transport before client", []Option{WithTransport(transport), WithHTTPClient(httpClient)}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requests = nil
+			client, err := NewEseisClient(append([]Option{WithConfig(testConfig())}, test.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if client.httpClient.Timeout != httpClient.Timeout || client.httpClient.Jar != jar {
+				t.Errorf("got timeout %s and jar %v, want the ones of the http client", client.httpClient.Timeout, client.httpClient.Jar)
+			}
+			if _, err = client.Authenticate(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if len(requests) != 1 {
+				t.Errorf("got %d requests through the transport, want 1", len(requests))
+			}
+			if httpClient.Transport != nil {
+				t.Error("the transport was set on the http client of the caller")
+			}
+		})
+	}
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	var requests []*http.Request
+	httpClient := &http.Client{Transport: tokenTransport(&requests)}
+	client, err := NewEseisClient(WithConfig(testConfig()), WithHTTPClient(httpClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.httpClient != httpClient {
+		t.Error("got another http client than the configured one")
+	}
+	if _, err = client.Authenticate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 {
+		t.Errorf("got %d requests through the http client, want 1", len(requests))
+	}
+}
@@ -0,0 +1,141 @@
+package eseis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const defaultPagerMaxPages = 1000
+
+// ErrTooManyPages is returned by a Pager reaching its maximum number of pages
+var ErrTooManyPages = errors.New("too many pages")
+
+// PageFunc fetches a page of items, pages start at 1 and a zero perPage uses the endpoint default
+type PageFunc[T any] func(ctx context.Context, page int, perPage int) ([]T, error)
+
+type pagerConfig struct {
+	perPage  int
+	maxPages int
+}
+
+// PagerOption customizes a Pager
+type PagerOption func(*pagerConfig)
+
+// WithPerPage sets the number of items requested per page, zero uses the endpoint default
+func WithPerPage(perPage int) PagerOption {
+	return func(c *pagerConfig) {
+		c.perPage = perPage
+	}
+}
+
+// WithMaxPages sets the maximum number of pages fetched before failing with ErrTooManyPages
+func WithMaxPages(maxPages int) PagerOption {
+	return func(c *pagerConfig) {
+		c.maxPages = maxPages
+	}
+}
+
+// Pager iterates over the items of a paginated list endpoint, fetching pages until an empty one.
+//
+//	pager := client.IterContractFolders(contractID)
+//	for pager.Next(ctx) {
+//		folder := pager.Item()
+//	}
+//	if err := pager.Err(); err != nil {
+//	}
+type Pager[T any] struct {
+	fetch  PageFunc[T]
+	config pagerConfig
+	page   int
+	items  []T
+	index  int
+	done   bool
+	err    error
+}
+
+// NewPager creates a Pager fetching the pages with fetch
+func NewPager[T any](fetch PageFunc[T], opts ...PagerOption) *Pager[T] {
+	config := pagerConfig{maxPages: defaultPagerMaxPages}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return &Pager[T]{fetch: fetch, config: config, index: -1}
+}
+
+// Next advances to the next item, fetching the next page if needed. It returns false at the end of the list or
+// on error, see Err.
+func (p *Pager[T]) Next(ctx context.Context) bool {
+	if p.done {
+		return false
+	}
+	p.index++
+	for p.index >= len(p.items) {
+		if p.page >= p.config.maxPages {
+			p.fail(fmt.Errorf("%w: stopped after %d pages", ErrTooManyPages, p.page))
+			return false
+		}
+		items, err := p.fetch(ctx, p.page+1, p.config.perPage)
+		if err != nil {
+			p.fail(fmt.Errorf("failed to fetch page %d: %w", p.page+1, err))
+			return false
+		}
+		p.page++
+		if len(items) == 0 {
+			p.done = true
+			p.items = nil
+			return false
+		}
+		p.items = items
+		p.index = 0
+	}
+	return true
+}
+
+// Item returns the current item
+func (p *Pager[T]) Item() T {
+	return p.items[p.index]
+}
+
+// Page returns the number of the last fetched page
+func (p *Pager[T]) Page() int {
+	return p.page
+}
+
+// Err returns the error which stopped the iteration, if any
+func (p *Pager[T]) Err() error {
+	return p.err
+}
+
+// All returns all the remaining items
+func (p *Pager[T]) All(ctx context.Context) ([]T, error) {
+	all := make([]T, 0)
+	for p.Next(ctx) {
+		all = append(all, p.Item())
+	}
+	return all, p.Err()
+}
+
+// ForEach calls fn with all the remaining items, stopping at the first error
+func (p *Pager[T]) ForEach(ctx context.Context, fn func(item T) error) error {
+	for p.Next(ctx) {
+		if err := fn(p.Item()); err != nil {
+			return err
+		}
+	}
+	return p.Err()
+}
+
+func (p *Pager[T]) fail(err error) {
+	p.err = err
+	p.done = true
+	p.items = nil
+}
+
+// pageQuery returns the pagination query parameters
+func pageQuery(page int, perPage int) string {
+	if perPage > 0 {
+		return fmt.Sprintf("page=%d&per_page=%d", page, perPage)
+	}
+	return fmt.Sprintf("page=%d", page)
+}
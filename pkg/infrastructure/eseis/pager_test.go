@@ -0,0 +1,118 @@
+package eseis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// pagesOf returns a PageFunc serving pages, the pages after the last one are empty.
+// It records the perPage of each call in perPages.
+func pagesOf(pages [][]int, perPages *[]int) PageFunc[int] {
+	return func(ctx context.Context, page int, perPage int) ([]int, error) {
+		*perPages = append(*perPages, perPage)
+		if page > len(pages) {
+			return nil, nil
+		}
+		return pages[page-1], nil
+	}
+}
+
+func TestPagerAll(t *testing.T) {
+	tests := []struct {
+		name      string
+		pages     [][]int
+		opts      []PagerOption
+		want      []int
+		wantPages int
+		wantErr   error
+	}{
+		{"empty", nil, nil, []int{}, 1, nil},
+		{"single page", [][]int{{1, 2}}, nil, []int{1, 2}, 2, nil},
+		{"several pages", [][]int{{1, 2}, {3}, {4, 5}}, nil, []int{1, 2, 3, 4, 5}, 4, nil},
+		{"within max pages", [][]int{{1}, {2}}, []PagerOption{WithMaxPages(3)}, []int{1, 2}, 3, nil},
+		{"too many pages", [][]int{{1}, {2}, {3}}, []PagerOption{WithMaxPages(2)}, []int{1, 2}, 2, ErrTooManyPages},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var perPages []int
+			pager := NewPager(pagesOf(test.pages, &perPages), test.opts...)
+			got, err := pager.All(context.Background())
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got items %v, want %v", got, test.want)
+			}
+			if pager.Page() != test.wantPages {
+				t.Errorf("got %d fetched pages, want %d", pager.Page(), test.wantPages)
+			}
+		})
+	}
+}
+
+func TestPagerPerPage(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []PagerOption
+		want int
+	}{
+		{"endpoint default", nil, 0},
+		{"custom", []PagerOption{WithPerPage(50)}, 50},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var perPages []int
+			pager := NewPager(pagesOf([][]int{{1}, {2}}, &perPages), test.opts...)
+			if _, err := pager.All(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			for _, perPage := range perPages {
+				if perPage != test.want {
+					t.Errorf("got per page %d, want %d", perPage, test.want)
+				}
+			}
+		})
+	}
+	if got := pageQuery(2, 0); got != "page=2" {
+		t.Errorf("got query %s for the endpoint default, want page=2", got)
+	}
+	if got := pageQuery(2, 50); got != "page=2&per_page=50" {
+		t.Errorf("got query %s, want page=2&per_page=50", got)
+	}
+}
+
+func TestPagerStopsAtTheFirstError(t *testing.T) {
+	fetchErr := errors.New("fetch failed")
+	calls := 0
+	pager := NewPager(func(ctx context.Context, page int, perPage int) ([]int, error) {
+		calls++
+		if page == 2 {
+			return nil, fetchErr
+		}
+		return []int{page}, nil
+	})
+
+	got, err := pager.All(context.Background())
+	if !errors.Is(err, fetchErr) {
+		t.Fatalf("got error %v, want the fetch error", err)
+	}
+	if !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("got items %v, want the items of the first page", got)
+	}
+	if pager.Next(context.Background()) || calls != 2 {
+		t.Errorf("got %d fetches, want the pager to stop after the failed page", calls)
+	}
+
+	itemErr := errors.New("item failed")
+	pager = NewPager(pagesOf([][]int{{1, 2}}, new([]int)))
+	seen := 0
+	err = pager.ForEach(context.Background(), func(item int) error {
+		seen++
+		return itemErr
+	})
+	if !errors.Is(err, itemErr) || seen != 1 {
+		t.Errorf("got error %v after %d items, want the item error after 1 item", err, seen)
+	}
+}
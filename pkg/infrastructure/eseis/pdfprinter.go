@@ -1,148 +1,157 @@
 package eseis
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"github.com/chromedp/cdproto/page"
 	"github.com/chromedp/chromedp"
-	"github.com/chromedp/chromedp/kb"
 	"github.com/idkw/eseisscrapper/pkg/infrastructure/chrome"
 	"github.com/sirupsen/logrus"
-	"math/rand"
-	"os"
-	"text/template"
-	"time"
 )
 
-type pdfRes struct {
-	buffer *[]byte
+// browser returns the chrome session, starting the browser on first use
+func (e *EseisClient) browser(ctx context.Context) (*chrome.Chrome, error) {
+	e.chromeMu.Lock()
+	defer e.chromeMu.Unlock()
+	return e.startBrowser(ctx)
 }
 
-type ChromeDp struct {
-	ctx *context.Context
-}
-
-func newChrome(URL string, username string, password string) (*chrome.Chrome, error) {
-	c := chrome.NewChrome()
-	err := login(c, URL, username, password)
+// webUIBrowser returns the chrome session logged in to the Eseis web UI, logging in on first use.
+// The login navigates the tab, tabMu must be held.
+func (e *EseisClient) webUIBrowser(ctx context.Context) (*chrome.Chrome, error) {
+	e.chromeMu.Lock()
+	defer e.chromeMu.Unlock()
+	chromeSession, err := e.startBrowser(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return c, nil
+	if e.chromeLoggedIn {
+		return chromeSession, nil
+	}
+	if e.loginErr != nil {
+		return nil, e.loginErr
+	}
+	if !e.config.FormLoginOnly && !e.selectors.Session.isEmpty() {
+		logrus.Infof("starting the web UI session from the API token with selector profile %s", e.selectors.Version)
+		err = e.tokenLogin(ctx, chromeSession)
+		if err == nil {
+			e.chromeLoggedIn = true
+			return chromeSession, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		logrus.Warnf("the web UI session from the API token with selector profile %s was rejected, falling back to the login form: %s",
+			e.selectors.Version, err)
+	} else if !e.config.FormLoginOnly {
+		logrus.Infof("selector profile %s has no session, using the login form", e.selectors.Version)
+	}
+	logrus.Infof("logging in to the web UI with selector profile %s", e.selectors.Version)
+	if err = e.login(ctx, chromeSession, e.console); err != nil {
+		// do not log in again for each screenshot
+		if ctx.Err() == nil {
+			e.loginErr = err
+		}
+		return nil, err
+	}
+	e.chromeLoggedIn = true
+	return chromeSession, nil
 }
 
-func login(c *chrome.Chrome, URL string, username string, password string) error {
-	tasks := chromedp.Tasks{
-		chromedp.EmulateViewport(799, 799),
-		chromedp.Navigate(URL),
-		chromedp.WaitVisible("#login-username"),
-		chromedp.SendKeys(`#login-username`, username+kb.Enter),
-		chromedp.WaitVisible("#login-password"),
-		chromedp.SendKeys(`#login-password`, password+kb.Enter),
-		chromedp.WaitVisible(".sc-eHWfIC"), // wait for co-owner balance to be visible
+// startBrowser starts the browser unless already started, chromeMu must be held
+func (e *EseisClient) startBrowser(ctx context.Context) (*chrome.Chrome, error) {
+	if e.chromeDisabled {
+		return nil, ErrChromeDisabled
+	}
+	if e.chromeSession != nil {
+		return e.chromeSession, nil
+	}
+	if e.chromeErr != nil {
+		return nil, e.chromeErr
 	}
-	return c.RunTasks(tasks)
+	logrus.Info("starting chrome")
+	chromeSession, err := chrome.NewChrome(ctx, e.config.Chrome)
+	if err != nil {
+		err = fmt.Errorf("failed to start chrome: %w", err)
+		// do not try to start a browser which cannot start again for each screenshot
+		if ctx.Err() == nil {
+			e.chromeErr = err
+		}
+		return nil, err
+	}
+	network := newNetworkTracker()
+	chromeSession.ListenTarget(network.onEvent)
+	console := newConsoleRecorder()
+	chromeSession.ListenTarget(console.onEvent)
+	e.chromeSession = chromeSession
+	e.network = network
+	e.console = console
+	return chromeSession, nil
 }
 
-func (e *EseisClient) SavePDF(URL string, outPath string, actions ...chromedp.Action) error {
-	var pdfRes = pdfRes{}
-
-	var savePDFActions []chromedp.Action
-	savePDFActions = append(savePDFActions, navigateAction(URL))
-	savePDFActions = append(savePDFActions, actions...)
-	savePDFActions = append(savePDFActions, printPdfAction(&pdfRes))
+// networkTracker returns the tracker of the browser tab requests, nil if the browser is not started
+func (e *EseisClient) networkTracker() *networkTracker {
+	e.chromeMu.Lock()
+	defer e.chromeMu.Unlock()
+	return e.network
+}
 
-	if err := e.chromeSession.RunTasks(savePDFActions); err != nil {
-		logrus.Fatal(err)
+// Close stops the browser if it was started, once the running capture is done
+func (e *EseisClient) Close() {
+	e.tabMu.Lock()
+	defer e.tabMu.Unlock()
+	e.chromeMu.Lock()
+	defer e.chromeMu.Unlock()
+	if e.chromeSession != nil {
+		e.chromeSession.Close()
+		e.chromeSession = nil
+		e.chromeLoggedIn = false
+		e.network = nil
+		e.console = nil
 	}
-	if err := os.WriteFile(outPath, *pdfRes.buffer, 0o644); err != nil {
-		logrus.Fatal(err)
-	}
-	return nil
 }
 
 func navigateAction(urlstr string) chromedp.Action {
 	return chromedp.Navigate(urlstr)
 }
 
-func WaitForReportPageActions() []chromedp.Action {
-	return []chromedp.Action{
-		chromedp.WaitReady(".sc-jQAxuV"),        // wait for title to be visible
-		chromedp.WaitReady(".sc-eDdKWq"),        // wait for author to be visible
-		chromedp.WaitReady(".sc-eHEENL"),        // wait for report description to be visible
-		chromedp.WaitReady(".sc-dWBRfb"),        // wait for comment to be visible
-		NewRemoveElementAction(".sc-kLDuD"),     // remove menu banner
-		NewRemovePaddingLeftAction(".sc-qFupO"), // remove menu banner left padding
-	}
-}
-func WaitForForumPageActions() []chromedp.Action {
-	return []chromedp.Action{
-		chromedp.WaitReady(".sc-jQAxuV"),        // wait for title to be visible
-		chromedp.WaitReady(".sc-eDdKWq"),        // wait for author to be visible
-		chromedp.WaitReady(".sc-jOFryr"),        // wait for report description to be visible
-		NewRemoveElementAction(".sc-kLDuD"),     // remove menu banner
-		NewRemovePaddingLeftAction(".sc-qFupO"), // remove menu banner left padding
-		chromedp.Sleep(2 * time.Second),
-	}
+// reportPageActions returns the actions preparing a report page for its screenshot
+func (e *EseisClient) reportPageActions() []chromedp.Action {
+	return e.pageActions(e.selectors.ReportPage)
 }
 
-func printPdfAction(res *pdfRes) chromedp.Action {
-	return chromedp.ActionFunc(func(ctx context.Context) error {
-		buf, _, err := page.PrintToPDF().
-			Do(ctx)
-		if err != nil {
-			return err
-		}
-		res.buffer = &buf
-		return nil
-	})
+// forumPageActions returns the actions preparing a forum topic page for its screenshot
+func (e *EseisClient) forumPageActions() []chromedp.Action {
+	return e.pageActions(e.selectors.ForumPage)
 }
 
-func NewRemoveElementAction(selector string) chromedp.Action {
-	tpl, err := template.New("js").
-		Parse("let {{ .Node }} = document.querySelector('{{ .Selector }}'); {{ .Node }}.parentNode.removeChild({{ .Node }}); true;")
-	if err != nil {
-		logrus.Warnf("Failed to create template to remove element %s", selector)
-		return nil
-	}
-	type ctx struct {
-		Node, Selector string
+// pageActions waits for the page elements, the network and the images, then cleans the page up for its screenshot
+func (e *EseisClient) pageActions(selectors PageSelectors) []chromedp.Action {
+	var actions []chromedp.Action
+	for _, selector := range selectors.WaitFor {
+		if selector.Optional {
+			actions = append(actions, e.waitOptionalAction(selector))
+		} else {
+			actions = append(actions, selector.waitReady())
+		}
 	}
-	buffer := bytes.Buffer{}
-	c := ctx{
-		Node:     fmt.Sprintf("node%d", rand.Int()),
-		Selector: selector,
+	actions = append(actions, e.waitPageReadyAction())
+	for _, selector := range selectors.Remove {
+		actions = append(actions, NewRemoveElementAction(selector))
 	}
-	err = tpl.Execute(&buffer, c)
-	if err != nil {
-		logrus.Warnf("Failed to execute template to remove element %s", selector)
-		return nil
+	for _, selector := range selectors.RemovePaddingLeft {
+		actions = append(actions, NewRemovePaddingLeftAction(selector))
 	}
-	javascript := buffer.String()
+	return actions
+}
+
+// NewRemoveElementAction removes the first element matching selector from the page, if any
+func NewRemoveElementAction(selector Selector) chromedp.Action {
+	javascript := fmt.Sprintf("(() => { const node = %s; if (node) { node.remove(); } return true; })()", selector.jsFindElement())
 	return chromedp.Evaluate(javascript, nil)
 }
 
-func NewRemovePaddingLeftAction(selector string) chromedp.Action {
-	tpl, err := template.New("js").
-		Parse("let {{ .Node }} = document.querySelector('{{ .Selector }}'); {{ .Node }}.style.paddingLeft = 0; true;")
-	if err != nil {
-		logrus.Warnf("Failed to create template to remove element %s", selector)
-		return nil
-	}
-	type ctx struct {
-		Node, Selector string
-	}
-	buffer := bytes.Buffer{}
-	c := ctx{
-		Node:     fmt.Sprintf("node%d", rand.Int()),
-		Selector: selector,
-	}
-	err = tpl.Execute(&buffer, c)
-	if err != nil {
-		logrus.Warnf("Failed to execute template to remove padding left %s", selector)
-		return nil
-	}
-	javascript := buffer.String()
+// NewRemovePaddingLeftAction removes the left padding of the first element matching selector, if any
+func NewRemovePaddingLeftAction(selector Selector) chromedp.Action {
+	javascript := fmt.Sprintf("(() => { const node = %s; if (node) { node.style.paddingLeft = 0; } return true; })()", selector.jsFindElement())
 	return chromedp.Evaluate(javascript, nil)
 }
@@ -0,0 +1,72 @@
+package eseis
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChromeDisabledDoesNotStartTheBrowser(t *testing.T) {
+	tests := []struct {
+		name   string
+		config func(config *Config)
+		opts   []Option
+	}{
+		{"option", func(config *Config) {}, []Option{WithoutChrome()}},
+		{"config", func(config *Config) { config.ChromeDisabled = true }, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			// the browser is a script leaving a file behind when started
+			startedPath := filepath.Join(dir, "started")
+			execPath := filepath.Join(dir, "chrome")
+			if err := os.WriteFile(execPath, []byte("#!/bin/sh\ntouch '"+startedPath+"'\nexit 1\n"), 0700); err != nil {
+				t.Fatal(err)
+			}
+			config := testConfig()
+			config.Chrome.ExecPath = execPath
+			test.config(&config)
+			client, err := NewEseisClient(append([]Option{WithConfig(config)}, test.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+
+			outDir := filepath.Join(dir, "out")
+			if err = os.Mkdir(outDir, 0770); err != nil {
+				t.Fatal(err)
+			}
+			htmlPath := filepath.Join(dir, "report.html")
+			if err = os.WriteFile(htmlPath, []byte("<html></html>"), 0660); err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+			steps := map[string]func() error{
+				"report screenshot": func() error {
+					return client.CreateReportScreenshot(ctx, ReportSummary{ID: 1, URL: "http://eseis/reports/1"}, outDir)
+				},
+				"forum topic screenshot": func() error {
+					return client.CreateForumTopicScreenshot(ctx, ForumTopic{ID: 2, URL: "http://eseis/forum/2"}, outDir)
+				},
+				"html print": func() error {
+					return client.PrintHTMLFile(ctx, htmlPath, filepath.Join(outDir, "report.pdf"), PrintStamp{})
+				},
+			}
+			for step, run := range steps {
+				if err = run(); !errors.Is(err, ErrChromeDisabled) {
+					t.Errorf("got %s error %v, want ErrChromeDisabled", step, err)
+				}
+			}
+
+			if _, err = os.Stat(startedPath); err == nil {
+				t.Error("chrome was started")
+			}
+			if entries, _ := os.ReadDir(outDir); len(entries) != 0 {
+				t.Errorf("got %d files written, want none", len(entries))
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package eseis
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"html/template"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPaperSize = "A4"
+	defaultScale     = 1
+)
+
+// paperSizes are the width and height in inches of the supported paper sizes
+var paperSizes = map[string][2]float64{
+	"A3":      {11.69, 16.54},
+	"A4":      {8.27, 11.69},
+	"A5":      {5.83, 8.27},
+	"LETTER":  {8.5, 11},
+	"LEGAL":   {8.5, 14},
+	"TABLOID": {11, 17},
+}
+
+// defaultHeaderTemplate and defaultFooterTemplate stamp the captures when PrintOptions.Stamp is set.
+// The pageNumber and totalPages classes are filled in by chrome.
+const (
+	defaultHeaderTemplate = `<div style="font-size: 8px; width: 100%; margin: 0 0.4in; display: flex; justify-content: space-between;">` +
+		`<span>{{ .URL }}</span><span>{{ .ItemID }}</span></div>`
+	defaultFooterTemplate = `<div style="font-size: 8px; width: 100%; margin: 0 0.4in; display: flex; justify-content: space-between;">` +
+		`<span>Captured on {{ .CapturedAt.Format "2006-01-02 15:04:05 MST" }}</span>` +
+		`<span><span class="pageNumber"></span>/<span class="totalPages"></span></span></div>`
+)
+
+// PrintOptions configures the pdf captures
+type PrintOptions struct {
+	// PaperSize is A3, A4, A5, Letter, Legal, Tabloid or a custom WIDTHxHEIGHT in inches such as 8.27x11.69
+	PaperSize string `env:"ESEIS_PDF_PAPER_SIZE" envDefault:"A4"`
+	Landscape bool   `env:"ESEIS_PDF_LANDSCAPE"`
+	// PrintBackground prints the background colours and images, true when nil
+	PrintBackground *bool `env:"ESEIS_PDF_PRINT_BACKGROUND"`
+	// Margins are in inches, they must leave room for the header and footer templates
+	MarginTop    float64 `env:"ESEIS_PDF_MARGIN_TOP" envDefault:"0.4"`
+	MarginBottom float64 `env:"ESEIS_PDF_MARGIN_BOTTOM" envDefault:"0.4"`
+	MarginLeft   float64 `env:"ESEIS_PDF_MARGIN_LEFT" envDefault:"0.4"`
+	MarginRight  float64 `env:"ESEIS_PDF_MARGIN_RIGHT" envDefault:"0.4"`
+	Scale        float64 `env:"ESEIS_PDF_SCALE" envDefault:"1"`
+	// PageRanges are the pages to print such as 1-5, 8, 11-13, all of them when empty
+	PageRanges string `env:"ESEIS_PDF_PAGE_RANGES"`
+	// Stamp prints the source url, the capture time and the item id on every page, using the default templates
+	// unless HeaderTemplate or FooterTemplate are set
+	Stamp bool `env:"ESEIS_PDF_STAMP"`
+	// HeaderTemplate and FooterTemplate are html/template templates executed with a PrintStamp, chrome fills in the
+	// elements with the date, title, url, pageNumber and totalPages classes
+	HeaderTemplate string `env:"ESEIS_PDF_HEADER_TEMPLATE"`
+	FooterTemplate string `env:"ESEIS_PDF_FOOTER_TEMPLATE"`
+}
+
+// PrintStamp is the data available to the header and footer templates
+type PrintStamp struct {
+	URL        string
+	ItemID     string
+	CapturedAt time.Time
+}
+
+func (o *PrintOptions) setDefaults() {
+	if o.PaperSize == "" {
+		o.PaperSize = defaultPaperSize
+	}
+	if o.Scale == 0 {
+		o.Scale = defaultScale
+	}
+	if o.Stamp && o.HeaderTemplate == "" && o.FooterTemplate == "" {
+		o.HeaderTemplate = defaultHeaderTemplate
+		o.FooterTemplate = defaultFooterTemplate
+	}
+}
+
+// printBackground tells whether the background colours and images are printed, they are unless disabled
+func (o *PrintOptions) printBackground() bool {
+	return o.PrintBackground == nil || *o.PrintBackground
+}
+
+func (o *PrintOptions) validate() error {
+	if _, _, err := o.paperSize(); err != nil {
+		return err
+	}
+	for _, margin := range []float64{o.MarginTop, o.MarginBottom, o.MarginLeft, o.MarginRight} {
+		if margin < 0 {
+			return fmt.Errorf("pdf margin %g must not be negative", margin)
+		}
+	}
+	// chrome rejects scales outside of this range
+	if o.Scale < 0.1 || o.Scale > 2 {
+		return fmt.Errorf("pdf scale %g must be between 0.1 and 2", o.Scale)
+	}
+	for _, tpl := range []string{o.HeaderTemplate, o.FooterTemplate} {
+		if _, err := template.New("").Parse(tpl); err != nil {
+			return fmt.Errorf("invalid pdf header or footer template: %w", err)
+		}
+	}
+	return nil
+}
+
+// paperSize returns the paper width and height in inches
+func (o *PrintOptions) paperSize() (width float64, height float64, err error) {
+	if size, ok := paperSizes[strings.ToUpper(o.PaperSize)]; ok {
+		return size[0], size[1], nil
+	}
+	widthStr, heightStr, ok := strings.Cut(strings.ToLower(o.PaperSize), "x")
+	if ok {
+		width, widthErr := strconv.ParseFloat(widthStr, 64)
+		height, heightErr := strconv.ParseFloat(heightStr, 64)
+		if widthErr == nil && heightErr == nil && width > 0 && height > 0 {
+			return width, height, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("unknown pdf paper size %q", o.PaperSize)
+}
+
+// printPdfAction prints the page with the print options, stamping it with stamp if templates are configured
+func printPdfAction(options PrintOptions, stamp PrintStamp, res *[]byte) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		width, height, err := options.paperSize()
+		if err != nil {
+			return err
+		}
+		params := page.PrintToPDF().
+			WithPaperWidth(width).
+			WithPaperHeight(height).
+			WithLandscape(options.Landscape).
+			WithPrintBackground(options.printBackground()).
+			WithMarginTop(options.MarginTop).
+			WithMarginBottom(options.MarginBottom).
+			WithMarginLeft(options.MarginLeft).
+			WithMarginRight(options.MarginRight).
+			WithScale(options.Scale).
+			WithPageRanges(options.PageRanges)
+		if options.HeaderTemplate != "" || options.FooterTemplate != "" {
+			header, err := executeStampTemplate(options.HeaderTemplate, stamp)
+			if err != nil {
+				return err
+			}
+			footer, err := executeStampTemplate(options.FooterTemplate, stamp)
+			if err != nil {
+				return err
+			}
+			// chrome prints its own default header or footer for an empty template
+			if header == "" {
+				header = "<span></span>"
+			}
+			if footer == "" {
+				footer = "<span></span>"
+			}
+			params = params.
+				WithDisplayHeaderFooter(true).
+				WithHeaderTemplate(header).
+				WithFooterTemplate(footer)
+		}
+		buf, _, err := params.Do(ctx)
+		if err != nil {
+			return err
+		}
+		*res = buf
+		return nil
+	})
+}
+
+func executeStampTemplate(text string, stamp PrintStamp) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	tpl, err := template.New("stamp").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse pdf stamp template: %w", err)
+	}
+	buffer := bytes.Buffer{}
+	if err = tpl.Execute(&buffer, stamp); err != nil {
+		return "", fmt.Errorf("failed to execute pdf stamp template: %w", err)
+	}
+	return buffer.String(), nil
+}
@@ -0,0 +1,167 @@
+package eseis
+
+import (
+	"github.com/caarlos0/env/v7"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrintOptionsPaperSize(t *testing.T) {
+	tests := []struct {
+		paperSize  string
+		wantWidth  float64
+		wantHeight float64
+		wantErr    bool
+	}{
+		{"A4", 8.27, 11.69, false},
+		{"letter", 8.5, 11, false},
+		{"Tabloid", 11, 17, false},
+		{"8.27x11.69", 8.27, 11.69, false},
+		{"5X7", 5, 7, false},
+		{"B4", 0, 0, true},
+		{"8.27x", 0, 0, true},
+		{"x11", 0, 0, true},
+		{"0x11", 0, 0, true},
+		{"-1x11", 0, 0, true},
+		{"widexhigh", 0, 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.paperSize, func(t *testing.T) {
+			options := PrintOptions{PaperSize: test.paperSize}
+			width, height, err := options.paperSize()
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %t", err, test.wantErr)
+			}
+			if width != test.wantWidth || height != test.wantHeight {
+				t.Errorf("got %gx%g, want %gx%g", width, height, test.wantWidth, test.wantHeight)
+			}
+		})
+	}
+}
+
+func TestPrintOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options PrintOptions
+		wantErr string
+	}{
+		{"defaults", PrintOptions{}, ""},
+		{"custom paper size and margins", PrintOptions{PaperSize: "8x10", MarginTop: 1, MarginBottom: 1.5}, ""},
+		{"min scale", PrintOptions{Scale: 0.1}, ""},
+		{"max scale", PrintOptions{Scale: 2}, ""},
+		{"scale too small", PrintOptions{Scale: 0.05}, "pdf scale"},
+		{"scale too large", PrintOptions{Scale: 2.5}, "pdf scale"},
+		{"negative scale", PrintOptions{Scale: -1}, "pdf scale"},
+		{"negative margin", PrintOptions{MarginLeft: -0.1}, "pdf margin"},
+		{"unknown paper size", PrintOptions{PaperSize: "B4"}, "paper size"},
+		{"invalid header", PrintOptions{HeaderTemplate: "{{ .URL "}, "template"},
+		{"invalid footer", PrintOptions{FooterTemplate: "{{ end }}"}, "template"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.options.setDefaults()
+			err := test.options.validate()
+			if test.wantErr == "" {
+				if err != nil {
+					t.Errorf("got error %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("got error %v, want an error about the %s", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestPrintOptionsFromEnv(t *testing.T) {
+	t.Setenv("ESEIS_PDF_PAPER_SIZE", "Letter")
+	t.Setenv("ESEIS_PDF_MARGIN_TOP", "1.25")
+	t.Setenv("ESEIS_PDF_MARGIN_LEFT", "0")
+	t.Setenv("ESEIS_PDF_SCALE", "0.8")
+
+	options := PrintOptions{}
+	if err := env.Parse(&options); err != nil {
+		t.Fatal(err)
+	}
+	want := PrintOptions{
+		PaperSize:    "Letter",
+		MarginTop:    1.25,
+		MarginBottom: 0.4,
+		MarginLeft:   0,
+		MarginRight:  0.4,
+		Scale:        0.8,
+	}
+	if options != want {
+		t.Errorf("got options %+v, want %+v", options, want)
+	}
+	if err := options.validate(); err != nil {
+		t.Errorf("got error %v, want none", err)
+	}
+
+	t.Setenv("ESEIS_PDF_MARGIN_BOTTOM", "1in")
+	if err := env.Parse(&PrintOptions{}); err == nil {
+		t.Error("got no error for a margin with a unit")
+	}
+}
+
+func TestPrintOptionsPrintBackground(t *testing.T) {
+	enabled, disabled := true, false
+	tests := []struct {
+		name    string
+		env     string
+		options PrintOptions
+		want    bool
+	}{
+		{"default from env", "", PrintOptions{}, true},
+		{"disabled from env", "false", PrintOptions{}, false},
+		{"enabled from env", "true", PrintOptions{}, true},
+		{"default from config", "-", PrintOptions{}, true},
+		{"disabled from config", "-", PrintOptions{PrintBackground: &disabled}, false},
+		{"enabled from config", "-", PrintOptions{PrintBackground: &enabled}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := test.options
+			if test.env != "-" {
+				if test.env != "" {
+					t.Setenv("ESEIS_PDF_PRINT_BACKGROUND", test.env)
+				}
+				if err := env.Parse(&options); err != nil {
+					t.Fatal(err)
+				}
+			}
+			options.setDefaults()
+			if got := options.printBackground(); got != test.want {
+				t.Errorf("got print background %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPrintOptionsSetDefaults(t *testing.T) {
+	options := PrintOptions{Stamp: true}
+	options.setDefaults()
+	if options.PaperSize != defaultPaperSize || options.Scale != defaultScale {
+		t.Errorf("got paper size %s and scale %g, want %s and %d", options.PaperSize, options.Scale, defaultPaperSize, defaultScale)
+	}
+	if options.HeaderTemplate != defaultHeaderTemplate || options.FooterTemplate != defaultFooterTemplate {
+		t.Error("got no default stamp templates")
+	}
+
+	// a custom template disables both default ones
+	options = PrintOptions{Stamp: true, FooterTemplate: "<span>{{ .ItemID }}</span>"}
+	options.setDefaults()
+	if options.HeaderTemplate != "" {
+		t.Errorf("got header template %q, want none next to a custom footer", options.HeaderTemplate)
+	}
+
+	footer, err := executeStampTemplate(options.FooterTemplate, PrintStamp{ItemID: "<42>", CapturedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if footer != "<span>&lt;42&gt;</span>" {
+		t.Errorf("got footer %q, want the escaped item id", footer)
+	}
+}
@@ -0,0 +1,166 @@
+package eseis
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRateLimitRPS   = 5
+	defaultRateLimitBurst = 5
+)
+
+// RateLimits configures the client side rate limiting of the Eseis API requests.
+// The global limit applies to every request, endpoint limits apply on top of it.
+type RateLimits struct {
+	// RequestsPerSecond is the global requests rate, negative to disable rate limiting
+	RequestsPerSecond float64 `env:"ESEIS_RATE_LIMIT_RPS" envDefault:"5"`
+	Burst             int     `env:"ESEIS_RATE_LIMIT_BURST" envDefault:"5"`
+	// DocumentsPerSecond is the /v1/sergic_documents requests rate, unlimited when zero
+	DocumentsPerSecond float64 `env:"ESEIS_RATE_LIMIT_DOCUMENTS_RPS"`
+	// AttachmentsPerSecond is the attachment downloads rate, unlimited when zero
+	AttachmentsPerSecond float64 `env:"ESEIS_RATE_LIMIT_ATTACHMENTS_RPS"`
+}
+
+func (r *RateLimits) setDefaults() {
+	if r.RequestsPerSecond == 0 {
+		r.RequestsPerSecond = defaultRateLimitRPS
+	}
+	if r.Burst <= 0 {
+		r.Burst = defaultRateLimitBurst
+	}
+}
+
+// RateLimiterStats are the statistics of one rate limiter bucket
+type RateLimiterStats struct {
+	Name              string
+	RequestsPerSecond float64
+	Burst             int
+	// Available is the number of requests which can currently be sent without waiting
+	Available float64
+	// Requests is the number of requests which went through the limiter
+	Requests int64
+	// Waited is the total time requests spent waiting for the limiter
+	Waited time.Duration
+}
+
+// rateLimiter holds the token buckets of the client, nil buckets are unlimited
+type rateLimiter struct {
+	global      *tokenBucket
+	documents   *tokenBucket
+	attachments *tokenBucket
+}
+
+func newRateLimiter(limits RateLimits) *rateLimiter {
+	return &rateLimiter{
+		global:      newTokenBucket("global", limits.RequestsPerSecond, limits.Burst),
+		documents:   newTokenBucket("documents", limits.DocumentsPerSecond, 1),
+		attachments: newTokenBucket("attachments", limits.AttachmentsPerSecond, 1),
+	}
+}
+
+func (l *rateLimiter) stats() []RateLimiterStats {
+	var stats []RateLimiterStats
+	for _, bucket := range []*tokenBucket{l.global, l.documents, l.attachments} {
+		if bucket != nil {
+			stats = append(stats, bucket.stats())
+		}
+	}
+	return stats
+}
+
+// tokenBucket is a token bucket rate limiter
+type tokenBucket struct {
+	name  string
+	rate  float64
+	burst int
+
+	mu       sync.Mutex
+	tokens   float64
+	last     time.Time
+	requests int64
+	waited   time.Duration
+}
+
+// newTokenBucket returns a full token bucket, or nil if rate is not positive
+func newTokenBucket(name string, rate float64, burst int) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	return &tokenBucket{
+		name:   name,
+		rate:   rate,
+		burst:  burst,
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// refill adds the tokens accumulated since the last call, must be called with mu held
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > float64(b.burst) {
+		b.tokens = float64(b.burst)
+	}
+	b.last = now
+}
+
+// wait takes a token from the bucket, blocking until one is available or ctx is done
+func (b *tokenBucket) wait(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	b.refill(time.Now())
+	// reserve the token right away so that concurrent callers queue up behind each other
+	b.tokens--
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.requests++
+	b.waited += delay
+	b.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		// give the reserved token back, the request is not sent
+		b.mu.Lock()
+		b.tokens++
+		b.requests--
+		b.waited -= delay
+		b.mu.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (b *tokenBucket) stats() RateLimiterStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(time.Now())
+	available := b.tokens
+	if available < 0 {
+		available = 0
+	}
+	return RateLimiterStats{
+		Name:              b.name,
+		RequestsPerSecond: b.rate,
+		Burst:             b.burst,
+		Available:         available,
+		Requests:          b.requests,
+		Waited:            b.waited,
+	}
+}
+
+// RateLimiterStats returns the current statistics of the client rate limiters
+func (e *EseisClient) RateLimiterStats() []RateLimiterStats {
+	return e.limiter.stats()
+}
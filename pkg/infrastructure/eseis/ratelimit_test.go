@@ -0,0 +1,86 @@
+package eseis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketWait(t *testing.T) {
+	tests := []struct {
+		name     string
+		rate     float64
+		burst    int
+		requests int
+		// minDelay is the minimum duration of all the waits
+		minDelay time.Duration
+	}{
+		{"within burst", 10, 3, 3, 0},
+		{"one over burst", 20, 2, 3, 50 * time.Millisecond},
+		{"two over burst", 20, 1, 3, 100 * time.Millisecond},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bucket := newTokenBucket(test.name, test.rate, test.burst)
+			start := time.Now()
+			for i := 0; i < test.requests; i++ {
+				if err := bucket.wait(context.Background()); err != nil {
+					t.Fatalf("wait %d failed: %s", i, err)
+				}
+			}
+			elapsed := time.Since(start)
+			if elapsed < test.minDelay {
+				t.Errorf("waited %s, want at least %s", elapsed, test.minDelay)
+			}
+			stats := bucket.stats()
+			if stats.Requests != int64(test.requests) {
+				t.Errorf("got %d requests, want %d", stats.Requests, test.requests)
+			}
+			// the waits are computed before the tokens accumulated while sleeping are counted
+			if stats.Waited < test.minDelay*3/4 || (test.minDelay == 0 && stats.Waited != 0) {
+				t.Errorf("got waited %s, want about %s", stats.Waited, test.minDelay)
+			}
+		})
+	}
+}
+
+func TestTokenBucketWaitCancelled(t *testing.T) {
+	bucket := newTokenBucket("test", 1, 1)
+	if err := bucket.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := bucket.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, want context.DeadlineExceeded", err)
+	}
+
+	stats := bucket.stats()
+	if stats.Requests != 1 {
+		t.Errorf("got %d requests, want the cancelled one not counted", stats.Requests)
+	}
+	if stats.Waited != 0 {
+		t.Errorf("got waited %s, want the cancelled wait not counted", stats.Waited)
+	}
+	// the token given back makes the next request wait for a single token, not two
+	bucket.mu.Lock()
+	tokens := bucket.tokens
+	bucket.mu.Unlock()
+	if tokens < -0.1 {
+		t.Errorf("got %.2f tokens, want the reserved token given back", tokens)
+	}
+}
+
+func TestNilTokenBucketDoesNotWait(t *testing.T) {
+	bucket := newTokenBucket("disabled", 0, 1)
+	if bucket != nil {
+		t.Fatalf("got bucket %+v for a zero rate, want nil", bucket)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := bucket.wait(ctx); err != nil {
+		t.Errorf("got error %v, want nil bucket to never wait", err)
+	}
+}
@@ -0,0 +1,146 @@
+package eseis
+
+import (
+	"context"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+const (
+	defaultReadinessQuietPeriod     = 500 * time.Millisecond
+	defaultReadinessTimeout         = 30 * time.Second
+	defaultReadinessOptionalTimeout = 3 * time.Second
+	readinessPollInterval           = 100 * time.Millisecond
+)
+
+// Readiness configures how a page is detected as ready for its screenshot
+type Readiness struct {
+	// QuietPeriod is how long the network must stay idle before the page is considered loaded
+	QuietPeriod time.Duration `env:"ESEIS_READINESS_QUIET_PERIOD" envDefault:"500ms"`
+	// MaxInflightRequests is the number of pending requests still considered idle, e.g. for long polling
+	MaxInflightRequests int `env:"ESEIS_READINESS_MAX_INFLIGHT_REQUESTS"`
+	// Timeout bounds the wait for network idle and images, the screenshot is taken anyway once it expires
+	Timeout time.Duration `env:"ESEIS_READINESS_TIMEOUT" envDefault:"30s"`
+	// OptionalTimeout is how long optional elements are waited for before being skipped
+	OptionalTimeout time.Duration `env:"ESEIS_READINESS_OPTIONAL_TIMEOUT" envDefault:"3s"`
+}
+
+func (r *Readiness) setDefaults() {
+	if r.QuietPeriod <= 0 {
+		r.QuietPeriod = defaultReadinessQuietPeriod
+	}
+	if r.Timeout <= 0 {
+		r.Timeout = defaultReadinessTimeout
+	}
+	if r.OptionalTimeout <= 0 {
+		r.OptionalTimeout = defaultReadinessOptionalTimeout
+	}
+}
+
+// networkTracker tracks the pending requests of the browser tab
+type networkTracker struct {
+	mu           sync.Mutex
+	inflight     map[network.RequestID]struct{}
+	lastActivity time.Time
+}
+
+func newNetworkTracker() *networkTracker {
+	return &networkTracker{inflight: make(map[network.RequestID]struct{}), lastActivity: time.Now()}
+}
+
+// onEvent updates the pending requests, it is called with every event of the tab
+func (t *networkTracker) onEvent(ev interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		t.inflight[ev.RequestID] = struct{}{}
+	case *network.EventLoadingFinished:
+		delete(t.inflight, ev.RequestID)
+	case *network.EventLoadingFailed:
+		delete(t.inflight, ev.RequestID)
+	default:
+		return
+	}
+	t.lastActivity = time.Now()
+}
+
+// isIdle returns true if at most maxInflight requests are pending and nothing happened for quietPeriod
+func (t *networkTracker) isIdle(maxInflight int, quietPeriod time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.inflight) <= maxInflight && time.Since(t.lastActivity) >= quietPeriod
+}
+
+// loadImagesScript makes lazy images load eagerly and returns true once all images are loaded
+const loadImagesScript = `Array.from(document.images).every(img => {
+	if (img.loading === "lazy") { img.loading = "eager"; }
+	return img.complete;
+})`
+
+// waitPageReadyAction waits for the network to be idle and all the images to be loaded.
+// The page is considered ready anyway after the readiness timeout, so that a stuck request only degrades the screenshot.
+func (e *EseisClient) waitPageReadyAction() chromedp.Action {
+	readiness := e.config.Readiness
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		tracker := e.networkTracker()
+		waitCtx, cancel := context.WithTimeout(ctx, readiness.Timeout)
+		defer cancel()
+		err := pollUntil(waitCtx, func(ctx context.Context) (bool, error) {
+			if tracker != nil && !tracker.isIdle(readiness.MaxInflightRequests, readiness.QuietPeriod) {
+				return false, nil
+			}
+			var imagesLoaded bool
+			if err := chromedp.Evaluate(loadImagesScript, &imagesLoaded).Do(ctx); err != nil {
+				return false, err
+			}
+			return imagesLoaded, nil
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			logrus.Warnf("page not ready after %s, taking the screenshot anyway: %s", readiness.Timeout, err)
+		}
+		return nil
+	})
+}
+
+// waitOptionalAction waits for an optional element, giving up silently after the optional timeout
+func (e *EseisClient) waitOptionalAction(selector Selector) chromedp.Action {
+	timeout := e.config.Readiness.OptionalTimeout
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		waitCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		if err := selector.waitReady().Do(waitCtx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			logrus.Debugf("optional element %s not found after %s, skipping it", selector, timeout)
+		}
+		return nil
+	})
+}
+
+// pollUntil calls done until it returns true, an error, or ctx is done
+func pollUntil(ctx context.Context, done func(ctx context.Context) (bool, error)) error {
+	ticker := time.NewTicker(readinessPollInterval)
+	defer ticker.Stop()
+	for {
+		ok, err := done(ctx)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
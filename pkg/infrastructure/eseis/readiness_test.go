@@ -0,0 +1,86 @@
+package eseis
+
+import (
+	"context"
+	"errors"
+	"github.com/chromedp/cdproto/network"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"testing"
+	"time"
+)
+
+// busyTracker returns a network tracker with a request which never completes
+func busyTracker() *networkTracker {
+	tracker := newNetworkTracker()
+	tracker.onEvent(&network.EventRequestWillBeSent{RequestID: "long-polling"})
+	return tracker
+}
+
+func TestWaitPageReadyContinuesAfterTheTimeout(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	timeout := 50 * time.Millisecond
+	client := &EseisClient{
+		config:  &Config{Readiness: Readiness{QuietPeriod: time.Millisecond, Timeout: timeout}},
+		network: busyTracker(),
+	}
+	start := time.Now()
+	if err := client.waitPageReadyAction().Do(context.Background()); err != nil {
+		t.Fatalf("got error %v, want the page considered ready after the timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed < timeout {
+		t.Errorf("page ready after %s, want it after the %s timeout", elapsed, timeout)
+	}
+	if entry := hook.LastEntry(); entry == nil || entry.Level != logrus.WarnLevel {
+		t.Errorf("got log %v, want a warning about the page not being ready", entry)
+	}
+}
+
+func TestWaitPageReadyStopsWhenCancelled(t *testing.T) {
+	client := &EseisClient{
+		config:  &Config{Readiness: Readiness{QuietPeriod: time.Millisecond, Timeout: time.Minute}},
+		network: busyTracker(),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := client.waitPageReadyAction().Do(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want the cancellation of the capture", err)
+	}
+}
+
+func TestNetworkTrackerIsIdle(t *testing.T) {
+	tests := []struct {
+		name        string
+		events      []interface{}
+		maxInflight int
+		want        bool
+	}{
+		{"no request", nil, 0, true},
+		{"pending request", []interface{}{&network.EventRequestWillBeSent{RequestID: "1"}}, 0, false},
+		{"tolerated pending request", []interface{}{&network.EventRequestWillBeSent{RequestID: "1"}}, 1, true},
+		{"finished request", []interface{}{
+			&network.EventRequestWillBeSent{RequestID: "1"},
+			&network.EventLoadingFinished{RequestID: "1"},
+		}, 0, true},
+		{"failed request", []interface{}{
+			&network.EventRequestWillBeSent{RequestID: "1"},
+			&network.EventLoadingFailed{RequestID: "1"},
+		}, 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracker := newNetworkTracker()
+			for _, event := range test.events {
+				tracker.onEvent(event)
+			}
+			if tracker.isIdle(test.maxInflight, time.Hour) {
+				t.Error("got idle, want the quiet period to be waited for")
+			}
+			if got := tracker.isIdle(test.maxInflight, 0); got != test.want {
+				t.Errorf("got idle %t, want %t", got, test.want)
+			}
+		})
+	}
+}
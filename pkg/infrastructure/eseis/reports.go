@@ -1,24 +1,22 @@
 package eseis
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
 
 type reportSummaryResponse struct {
-	ID          int       `json:"id"`
-	DisplayName string    `json:"display_name"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	State       string    `json:"state"`
-	Author      struct {
-		DisplayPlaceRole string `json:"display_place_role"`
-		DisplayName      string `json:"display_name"`
-	} `json:"author,omitempty"`
+	ID          int            `json:"id"`
+	DisplayName string         `json:"display_name"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	State       string         `json:"state"`
+	Author      authorResponse `json:"author,omitempty"`
 }
 
 type ReportSummary struct {
@@ -27,20 +25,18 @@ type ReportSummary struct {
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 	State       string
-	Author      struct {
-		DisplayPlaceRole string
-		DisplayName      string
-	}
-	URL string
+	Author      Author
+	URL         string
 }
 
 func (r ReportSummary) CleanDisplayName() any {
 	return strings.Trim(strings.ReplaceAll(r.DisplayName, "/", "_"), "")
 }
 
-type Author struct {
-	DisplayPlaceRole string
-	DisplayName      string
+// FileBaseName returns the name, without extension, of the files and dir exported for the report
+func (r ReportSummary) FileBaseName() string {
+	year, month, day := r.CreatedAt.Date()
+	return fmt.Sprintf("%d_%d_%d__%d__%s", year, month, day, r.ID, r.CleanDisplayName())
 }
 
 type reportResponse struct {
@@ -69,126 +65,70 @@ type reportResponse struct {
 		UpdatedAt    time.Time `json:"updated_at"`
 		Sort         int       `json:"sort"`
 	} `json:"category"`
-	CanResolve       bool        `json:"can_resolve"`
-	ReportResolution interface{} `json:"report_resolution"`
-	Attachments      []struct {
-		ID                int         `json:"id"`
-		UUID              string      `json:"uuid"`
-		SourceFileName    string      `json:"source_file_name"`
-		SourceContentType string      `json:"source_content_type"`
-		SourceFileSize    int         `json:"source_file_size"`
-		FileURL           string      `json:"file_url"`
-		Dimensions        []int       `json:"dimensions"`
-		SourceUpdatedAt   time.Time   `json:"source_updated_at"`
-		DisplayName       interface{} `json:"display_name"`
-		Width             int         `json:"width"`
-		Height            int         `json:"height"`
-	} `json:"attachments"`
-	ReportEvents []struct {
-		ID          int       `json:"id"`
-		Description string    `json:"description"`
-		Kind        string    `json:"kind"`
-		CreatedAt   time.Time `json:"created_at"`
-		UpdatedAt   time.Time `json:"updated_at"`
-		DisplayName string    `json:"display_name"`
-		Author      struct {
-			ID               int         `json:"id"`
-			TopPlaceRole     string      `json:"top_place_role"`
-			InvitedBy        interface{} `json:"invited_by"`
-			RealName         string      `json:"real_name"`
-			IdentityID       int         `json:"identity_id"`
-			DisplayPlaceRole string      `json:"display_place_role"`
-			DisplayName      string      `json:"display_name"`
-			AvatarURL        string      `json:"avatar_url"`
-			SergicPartner    bool        `json:"sergic_partner"`
-			CanTalk          bool        `json:"can_talk"`
-			Contracts        []struct {
-				ID                      int    `json:"id"`
-				DisplayName             string `json:"display_name"`
-				PendingAmount           int    `json:"pending_amount"`
-				CustomerReferenceNumber string `json:"customer_reference_number"`
-			} `json:"contracts"`
-		} `json:"author"`
-		Attachments []struct {
-			ID                int         `json:"id"`
-			UUID              string      `json:"uuid"`
-			SourceFileName    string      `json:"source_file_name"`
-			SourceContentType string      `json:"source_content_type"`
-			SourceFileSize    int         `json:"source_file_size"`
-			FileURL           string      `json:"file_url"`
-			Dimensions        []int       `json:"dimensions"`
-			SourceUpdatedAt   time.Time   `json:"source_updated_at"`
-			DisplayName       interface{} `json:"display_name"`
-			Width             int         `json:"width"`
-			Height            int         `json:"height"`
-		} `json:"attachments"`
-	} `json:"report_events"`
+	CanResolve       bool                  `json:"can_resolve"`
+	ReportResolution interface{}           `json:"report_resolution"`
+	Attachments      []attachmentResponse  `json:"attachments"`
+	ReportEvents     []reportEventResponse `json:"report_events"`
+}
+
+type reportEventResponse struct {
+	ID          int                  `json:"id"`
+	Description string               `json:"description"`
+	Kind        string               `json:"kind"`
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+	DisplayName string               `json:"display_name"`
+	Author      authorResponse       `json:"author"`
+	Attachments []attachmentResponse `json:"attachments"`
 }
 
 type Report struct {
-	ID          int       `json:"id"`
-	DisplayName string    `json:"display_name"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	State       string    `json:"state"`
-	Attachments []struct {
-		ID                int         `json:"id"`
-		UUID              string      `json:"uuid"`
-		SourceFileName    string      `json:"source_file_name"`
-		SourceContentType string      `json:"source_content_type"`
-		SourceFileSize    int         `json:"source_file_size"`
-		FileURL           string      `json:"file_url"`
-		Dimensions        []int       `json:"dimensions"`
-		SourceUpdatedAt   time.Time   `json:"source_updated_at"`
-		DisplayName       interface{} `json:"display_name"`
-		Width             int         `json:"width"`
-		Height            int         `json:"height"`
-	} `json:"attachments"`
-	ReportEvents []struct {
-		ID          int       `json:"id"`
-		Description string    `json:"description"`
-		Kind        string    `json:"kind"`
-		CreatedAt   time.Time `json:"created_at"`
-		UpdatedAt   time.Time `json:"updated_at"`
-		DisplayName string    `json:"display_name"`
-		Author      struct {
-			ID               int         `json:"id"`
-			TopPlaceRole     string      `json:"top_place_role"`
-			InvitedBy        interface{} `json:"invited_by"`
-			RealName         string      `json:"real_name"`
-			IdentityID       int         `json:"identity_id"`
-			DisplayPlaceRole string      `json:"display_place_role"`
-			DisplayName      string      `json:"display_name"`
-			AvatarURL        string      `json:"avatar_url"`
-			SergicPartner    bool        `json:"sergic_partner"`
-			CanTalk          bool        `json:"can_talk"`
-			Contracts        []struct {
-				ID                      int    `json:"id"`
-				DisplayName             string `json:"display_name"`
-				PendingAmount           int    `json:"pending_amount"`
-				CustomerReferenceNumber string `json:"customer_reference_number"`
-			} `json:"contracts"`
-		} `json:"author"`
-		Attachments []struct {
-			ID                int         `json:"id"`
-			UUID              string      `json:"uuid"`
-			SourceFileName    string      `json:"source_file_name"`
-			SourceContentType string      `json:"source_content_type"`
-			SourceFileSize    int         `json:"source_file_size"`
-			FileURL           string      `json:"file_url"`
-			Dimensions        []int       `json:"dimensions"`
-			SourceUpdatedAt   time.Time   `json:"source_updated_at"`
-			DisplayName       interface{} `json:"display_name"`
-			Width             int         `json:"width"`
-			Height            int         `json:"height"`
-		} `json:"attachments"`
-	} `json:"report_events"`
+	ID           int           `json:"id"`
+	DisplayName  string        `json:"display_name"`
+	Description  string        `json:"description"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+	State        string        `json:"state"`
+	Attachments  []Attachment  `json:"attachments"`
+	ReportEvents []ReportEvent `json:"report_events"`
+}
+
+// ReportEvent is a comment or a state change of a report
+type ReportEvent struct {
+	ID          int          `json:"id"`
+	Description string       `json:"description"`
+	Kind        string       `json:"kind"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	DisplayName string       `json:"display_name"`
+	Author      Author       `json:"author"`
+	Attachments []Attachment `json:"attachments"`
+}
+
+// defaultReportSummariesPerPage is the number of items per page requested by GetReportSummaries
+const defaultReportSummariesPerPage = 10
+
+// GetReportSummaries returns a page of the reports of a place, pages start at 1. See IterReportSummaries to go through all of them.
+func (e *EseisClient) GetReportSummaries(ctx context.Context, placeID int, page int) ([]ReportSummary, error) {
+	return e.getReportSummariesPage(ctx, placeID, page, defaultReportSummariesPerPage)
 }
 
-func (e *EseisClient) GetReportSummaries(placeID int, page int) ([]ReportSummary, error) {
-	path := fmt.Sprintf("/v1/places/%d/reports?page=%d&per_page=10&sort=created_at", placeID, page)
-	req, err := http.NewRequest("GET", e.buildURL(path), nil)
+// IterReportSummaries returns a Pager over all the reports of a place
+func (e *EseisClient) IterReportSummaries(placeID int, opts ...PagerOption) *Pager[ReportSummary] {
+	fetch := func(ctx context.Context, page int, perPage int) ([]ReportSummary, error) {
+		return e.getReportSummariesPage(ctx, placeID, page, perPage)
+	}
+	return NewPager(fetch, append([]PagerOption{WithPerPage(defaultReportSummariesPerPage)}, opts...)...)
+}
+
+// AllReportSummaries returns all the reports of a place
+func (e *EseisClient) AllReportSummaries(ctx context.Context, placeID int, opts ...PagerOption) ([]ReportSummary, error) {
+	return e.IterReportSummaries(placeID, opts...).All(ctx)
+}
+
+func (e *EseisClient) getReportSummariesPage(ctx context.Context, placeID int, page int, perPage int) ([]ReportSummary, error) {
+	path := fmt.Sprintf("/v1/places/%d/reports?%s&sort=created_at", placeID, pageQuery(page, perPage))
+	req, err := e.newRequest(ctx, "GET", e.buildURL(path), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create report summaries request: %w", err)
 	}
@@ -196,7 +136,7 @@ func (e *EseisClient) GetReportSummaries(placeID int, page int) ([]ReportSummary
 		return nil, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := e.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send report summaries request: %w", err)
 	}
@@ -216,19 +156,16 @@ func (e *EseisClient) GetReportSummaries(placeID int, page int) ([]ReportSummary
 			CreatedAt:   report.CreatedAt,
 			UpdatedAt:   report.UpdatedAt,
 			State:       report.State,
-			Author: Author{
-				DisplayName:      report.Author.DisplayName,
-				DisplayPlaceRole: report.Author.DisplayPlaceRole,
-			},
-			URL: e.buildWebURL(fmt.Sprintf("/mes-echanges/signalements/%d", report.ID)),
+			Author:      report.Author.toAuthor(),
+			URL:         e.buildWebURL(fmt.Sprintf("/mes-echanges/signalements/%d", report.ID)),
 		}
 	}
 	return reports, nil
 }
 
-func (e *EseisClient) GetReport(reportID int) (Report, error) {
+func (e *EseisClient) GetReport(ctx context.Context, reportID int) (Report, error) {
 	path := fmt.Sprintf("/v1/reports/%d", reportID)
-	req, err := http.NewRequest("GET", e.buildURL(path), nil)
+	req, err := e.newRequest(ctx, "GET", e.buildURL(path), nil)
 	if err != nil {
 		return Report{}, fmt.Errorf("failed to create report request: %w", err)
 	}
@@ -236,7 +173,7 @@ func (e *EseisClient) GetReport(reportID int) (Report, error) {
 		return Report{}, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := e.do(req)
 	if err != nil {
 		return Report{}, fmt.Errorf("failed to send report request: %w", err)
 	}
@@ -248,6 +185,19 @@ func (e *EseisClient) GetReport(reportID int) (Report, error) {
 		return Report{}, fmt.Errorf("failed to decode report response: %w", err)
 	}
 
+	reportEvents := make([]ReportEvent, len(rawReport.ReportEvents))
+	for i, event := range rawReport.ReportEvents {
+		reportEvents[i] = ReportEvent{
+			ID:          event.ID,
+			Description: event.Description,
+			Kind:        event.Kind,
+			CreatedAt:   event.CreatedAt,
+			UpdatedAt:   event.UpdatedAt,
+			DisplayName: event.DisplayName,
+			Author:      event.Author.toAuthor(),
+			Attachments: toAttachments(event.Attachments),
+		}
+	}
 	return Report{
 		ID:           rawReport.ID,
 		DisplayName:  rawReport.DisplayName,
@@ -255,15 +205,13 @@ func (e *EseisClient) GetReport(reportID int) (Report, error) {
 		CreatedAt:    rawReport.CreatedAt,
 		UpdatedAt:    rawReport.UpdatedAt,
 		State:        rawReport.State,
-		Attachments:  rawReport.Attachments,
-		ReportEvents: rawReport.ReportEvents,
+		Attachments:  toAttachments(rawReport.Attachments),
+		ReportEvents: reportEvents,
 	}, nil
 }
 
-func (e *EseisClient) CreateReportScreenshot(report ReportSummary, outDir string) error {
-	reportName := strings.Trim(strings.ReplaceAll(report.DisplayName, "/", "_"), "")
-	year, month, day := report.CreatedAt.Date()
-	reportFileName := fmt.Sprintf("%d_%d_%d__%d__%s.pdf", year, month, day, report.ID, reportName)
-	reportPath := filepath.Join(outDir, reportFileName)
-	return e.SavePDF(report.URL, reportPath, WaitForReportPageActions()...)
+// CreateReportScreenshot captures the report page to outDir in each configured capture format
+func (e *EseisClient) CreateReportScreenshot(ctx context.Context, report ReportSummary, outDir string) error {
+	reportPath := filepath.Join(outDir, report.FileBaseName())
+	return e.Capture(ctx, report.URL, strconv.Itoa(report.ID), reportPath, e.config.CaptureFormats, e.reportPageActions()...)
 }
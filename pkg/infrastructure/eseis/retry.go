@@ -0,0 +1,106 @@
+package eseis
+
+import (
+	"context"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultRetryMaxAttempts = 4
+	defaultRetryBaseDelay   = time.Second
+	defaultRetryMaxDelay    = 30 * time.Second
+)
+
+// RetryPolicy configures how failed idempotent requests are retried
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, 1 disables retries
+	MaxAttempts int `env:"ESEIS_RETRY_MAX_ATTEMPTS" envDefault:"4"`
+	// BaseDelay is the delay before the first retry, doubled on each subsequent retry
+	BaseDelay time.Duration `env:"ESEIS_RETRY_BASE_DELAY" envDefault:"1s"`
+	// MaxDelay caps the exponential backoff delay and the delays requested by Retry-After headers
+	MaxDelay time.Duration `env:"ESEIS_RETRY_MAX_DELAY" envDefault:"30s"`
+}
+
+func (p *RetryPolicy) setDefaults() {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaultRetryMaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = defaultRetryBaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = defaultRetryMaxDelay
+	}
+}
+
+// backoff returns the delay to wait before the given retry (starting at 1), with jitter
+func (p RetryPolicy) backoff(retry int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		if apiErr.RetryAfter > p.MaxDelay {
+			logrus.Warnf("eseis api requested to retry %s in %s, waiting %s instead", apiErr.URL, apiErr.RetryAfter, p.MaxDelay)
+			return p.MaxDelay
+		}
+		return apiErr.RetryAfter
+	}
+	delay := p.BaseDelay << (retry - 1)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	// full jitter on the upper half of the delay to spread concurrent retries
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// isRetryableError returns true for transport errors, rate limiting and transient server errors
+func isRetryableError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrUnexpectedContentType) || errors.Is(err, errPartialDownload) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	// any other error comes from the transport (connection reset, unexpected EOF, ...)
+	return true
+}
+
+// isIdempotent returns true for requests which can safely be sent several times
+func isIdempotent(req *http.Request) bool {
+	return req.Method == http.MethodGet || req.Method == http.MethodHead || req.URL.Path == oauthTokenPath
+}
+
+// withRetry calls fn until it succeeds, returns a non retryable error or the retry policy is exhausted
+func (e *EseisClient) withRetry(ctx context.Context, description string, fn func() error) error {
+	policy := e.config.Retry
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= policy.MaxAttempts || !isRetryableError(err) {
+			return err
+		}
+		delay := policy.backoff(attempt, err)
+		logrus.Warnf("attempt %d/%d of %s failed, retrying in %s: %s", attempt, policy.MaxAttempts, description, delay, err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package eseis
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoffClampsRetryAfter(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 4, BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+	tests := []struct {
+		name       string
+		retryAfter time.Duration
+		want       time.Duration
+	}{
+		{"honored", 10 * time.Second, 10 * time.Second},
+		{"clamped", 24 * time.Hour, 30 * time.Second},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := &APIError{StatusCode: http.StatusTooManyRequests, URL: "http://eseis/v1/test", RetryAfter: test.retryAfter}
+			if got := policy.backoff(1, err); got != test.want {
+				t.Errorf("got backoff %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+// newRetryTestClient returns a client whose requests are answered with the given status codes, one per attempt,
+// and a pointer to the number of attempts
+func newRetryTestClient(t *testing.T, maxAttempts int, statusCodes ...int) (*EseisClient, *int32) {
+	t.Helper()
+	var attempts int32
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempt := atomic.AddInt32(&attempts, 1)
+		statusCode := statusCodes[len(statusCodes)-1]
+		if int(attempt) <= len(statusCodes) {
+			statusCode = statusCodes[attempt-1]
+		}
+		if statusCode == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "1")
+		}
+		w.WriteHeader(statusCode)
+	}))
+	client.config.Retry = RetryPolicy{MaxAttempts: maxAttempts, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Second}
+	return client, &attempts
+}
+
+func TestDoRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		maxAttempts  int
+		statusCodes  []int
+		wantStatus   int
+		wantAttempts int32
+	}{
+		{"5xx GET is retried", http.MethodGet, 4, []int{http.StatusServiceUnavailable, http.StatusOK}, http.StatusOK, 2},
+		{"POST is never retried", http.MethodPost, 4, []int{http.StatusServiceUnavailable, http.StatusOK}, http.StatusServiceUnavailable, 1},
+		{"client error is not retried", http.MethodGet, 4, []int{http.StatusNotFound, http.StatusOK}, http.StatusNotFound, 1},
+		{"max attempts is respected", http.MethodGet, 3, []int{http.StatusInternalServerError}, http.StatusInternalServerError, 3},
+		{"single attempt", http.MethodGet, 1, []int{http.StatusBadGateway, http.StatusOK}, http.StatusBadGateway, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, attempts := newRetryTestClient(t, test.maxAttempts, test.statusCodes...)
+			req, err := client.newRequest(context.Background(), test.method, client.buildURL("/v1/test"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := client.do(req)
+			statusCode := 0
+			var apiErr *APIError
+			if err == nil {
+				resp.Body.Close()
+				statusCode = resp.StatusCode
+			} else if errors.As(err, &apiErr) {
+				statusCode = apiErr.StatusCode
+			} else {
+				t.Fatalf("got error %v, want an API error", err)
+			}
+			if statusCode != test.wantStatus {
+				t.Errorf("got status %d, want %d", statusCode, test.wantStatus)
+			}
+			if *attempts != test.wantAttempts {
+				t.Errorf("got %d attempts, want %d", *attempts, test.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestDoHonoursRetryAfter(t *testing.T) {
+	client, attempts := newRetryTestClient(t, 4, http.StatusTooManyRequests, http.StatusOK)
+	req, err := client.newRequest(context.Background(), http.MethodGet, client.buildURL("/v1/test"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	resp, err := client.do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	// the base delay alone would retry after a few milliseconds
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %s, want the 1s of the Retry-After header", elapsed)
+	}
+	if *attempts != 2 {
+		t.Errorf("got %d attempts, want 2", *attempts)
+	}
+}
@@ -0,0 +1,259 @@
+package eseis
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"github.com/chromedp/chromedp"
+	"os"
+	"strings"
+)
+
+// defaultSelectorProfile is the selector profile matching the Eseis web UI at the time of the last release
+//
+//go:embed profiles/default.json
+var defaultSelectorProfile []byte
+
+// Selector locates an element of the Eseis web UI. Exactly one of CSS, XPath, Attribute or Text must be set.
+// Attribute and text selectors should be preferred, the generated css classes change with each frontend deploy.
+type Selector struct {
+	CSS   string `json:"css,omitempty"`
+	XPath string `json:"xpath,omitempty"`
+	// Attribute matches the elements having this attribute, with the given Value if not empty
+	Attribute string `json:"attribute,omitempty"`
+	Value     string `json:"value,omitempty"`
+	// Text matches the elements with a text node containing Text
+	Text string `json:"text,omitempty"`
+	// Tag restricts attribute and text selectors to elements with this tag name
+	Tag string `json:"tag,omitempty"`
+	// Optional elements are only waited for until the readiness optional timeout, they may be missing from the page
+	Optional    bool   `json:"optional,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// LoginSelectors are the selectors of the login page.
+// The default profile has no cookie banner, invalid credentials or unavailable selectors as these pages of the web UI
+// were never captured, a login failure is then reported as ErrLoginPageChanged. A custom profile can set them, e.g.
+//
+//	"invalidCredentials": [{"text": "<message shown for a wrong password>", "description": "login error message"}]
+type LoginSelectors struct {
+	Username Selector `json:"username"`
+	Password Selector `json:"password"`
+	// LoggedIn is an element only visible once logged in
+	LoggedIn Selector `json:"loggedIn"`
+	// CookieBanner are the buttons clicked to dismiss the banners covering the login form
+	CookieBanner []Selector `json:"cookieBanner"`
+	// InvalidCredentials are the elements showing that the username or the password was rejected
+	InvalidCredentials []Selector `json:"invalidCredentials"`
+	// Unavailable are the elements of the maintenance and error pages
+	Unavailable []Selector `json:"unavailable"`
+}
+
+// PageSelectors are the selectors of a page to screenshot
+type PageSelectors struct {
+	// WaitFor are the elements to wait for before the screenshot
+	WaitFor []Selector `json:"waitFor"`
+	// Remove are the elements removed from the page before the screenshot
+	Remove []Selector `json:"remove"`
+	// RemovePaddingLeft are the elements whose left padding is removed before the screenshot
+	RemovePaddingLeft []Selector `json:"removePaddingLeft"`
+}
+
+// SelectorProfile is a versioned set of selectors of the Eseis web UI
+type SelectorProfile struct {
+	Version    string         `json:"version"`
+	Login      LoginSelectors `json:"login"`
+	ReportPage PageSelectors  `json:"reportPage"`
+	ForumPage  PageSelectors  `json:"forumPage"`
+	// Session starts the web UI session from the API token, the login form is used when it is empty or rejected.
+	// The default profile has none as the storage of the web UI session is not known, a custom profile can set it.
+	Session SessionProfile `json:"session"`
+}
+
+// DefaultSelectorProfile returns the selector profile embedded in the binary
+func DefaultSelectorProfile() (*SelectorProfile, error) {
+	profile := &SelectorProfile{}
+	if err := json.Unmarshal(defaultSelectorProfile, profile); err != nil {
+		return nil, fmt.Errorf("failed to decode default selector profile: %w", err)
+	}
+	return profile, nil
+}
+
+// LoadSelectorProfile returns the default selector profile overridden by the json profile at path.
+// Each selector, list of selectors or session map present in the file replaces the default one as a whole,
+// the keys missing from the file keep their default value.
+func LoadSelectorProfile(path string) (*SelectorProfile, error) {
+	profile, err := DefaultSelectorProfile()
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read selector profile %s: %w", path, err)
+	}
+	// decoding into the default profile would merge the maps and the list elements with the default ones
+	override := &SelectorProfile{}
+	if err = json.Unmarshal(content, override); err != nil {
+		return nil, fmt.Errorf("failed to decode selector profile %s: %w", path, err)
+	}
+	profile.override(override)
+	if err = profile.validate(); err != nil {
+		return nil, fmt.Errorf("invalid selector profile %s: %w", path, err)
+	}
+	return profile, nil
+}
+
+// override replaces the values of p by the ones set in o
+func (p *SelectorProfile) override(o *SelectorProfile) {
+	if o.Version != "" {
+		p.Version = o.Version
+	}
+	overrideSelector(&p.Login.Username, o.Login.Username)
+	overrideSelector(&p.Login.Password, o.Login.Password)
+	overrideSelector(&p.Login.LoggedIn, o.Login.LoggedIn)
+	overrideSelectors(&p.Login.CookieBanner, o.Login.CookieBanner)
+	overrideSelectors(&p.Login.InvalidCredentials, o.Login.InvalidCredentials)
+	overrideSelectors(&p.Login.Unavailable, o.Login.Unavailable)
+	p.ReportPage.override(o.ReportPage)
+	p.ForumPage.override(o.ForumPage)
+	if o.Session.LocalStorage != nil {
+		p.Session.LocalStorage = o.Session.LocalStorage
+	}
+	if o.Session.Cookies != nil {
+		p.Session.Cookies = o.Session.Cookies
+	}
+}
+
+func (p *PageSelectors) override(o PageSelectors) {
+	overrideSelectors(&p.WaitFor, o.WaitFor)
+	overrideSelectors(&p.Remove, o.Remove)
+	overrideSelectors(&p.RemovePaddingLeft, o.RemovePaddingLeft)
+}
+
+func overrideSelector(selector *Selector, override Selector) {
+	if override != (Selector{}) {
+		*selector = override
+	}
+}
+
+// overrideSelectors replaces selectors unless the list is missing from the profile, an empty list clears them
+func overrideSelectors(selectors *[]Selector, override []Selector) {
+	if override != nil {
+		*selectors = override
+	}
+}
+
+func (p *SelectorProfile) validate() error {
+	selectors := []Selector{p.Login.Username, p.Login.Password, p.Login.LoggedIn}
+	selectors = append(selectors, p.Login.CookieBanner...)
+	selectors = append(selectors, p.Login.InvalidCredentials...)
+	selectors = append(selectors, p.Login.Unavailable...)
+	for _, page := range []PageSelectors{p.ReportPage, p.ForumPage} {
+		selectors = append(selectors, page.WaitFor...)
+		selectors = append(selectors, page.Remove...)
+		selectors = append(selectors, page.RemovePaddingLeft...)
+	}
+	for _, selector := range selectors {
+		if err := selector.validate(); err != nil {
+			return err
+		}
+	}
+	return p.Session.validate()
+}
+
+func (s Selector) validate() error {
+	set := 0
+	for _, value := range []string{s.CSS, s.XPath, s.Attribute, s.Text} {
+		if value != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("selector %s must have exactly one of css, xpath, attribute or text", s)
+	}
+	return nil
+}
+
+// String returns the selector query, followed by its description if any
+func (s Selector) String() string {
+	query, _ := s.query()
+	if s.Description != "" {
+		return fmt.Sprintf("%q (%s)", query, s.Description)
+	}
+	return fmt.Sprintf("%q", query)
+}
+
+// query returns the css or xpath query of the selector, isXPath tells which one it is
+func (s Selector) query() (query string, isXPath bool) {
+	switch {
+	case s.XPath != "":
+		return s.XPath, true
+	case s.Text != "":
+		tag := s.Tag
+		if tag == "" {
+			tag = "*"
+		}
+		return fmt.Sprintf("//%s[text()[contains(normalize-space(.), %s)]]", tag, xpathLiteral(s.Text)), true
+	case s.Attribute != "":
+		if s.Value == "" {
+			return fmt.Sprintf("%s[%s]", s.Tag, s.Attribute), false
+		}
+		value, _ := json.Marshal(s.Value)
+		return fmt.Sprintf("%s[%s=%s]", s.Tag, s.Attribute, value), false
+	}
+	return s.CSS, false
+}
+
+// queryOptions returns the chromedp query options matching the selector kind
+func (s Selector) queryOptions() []chromedp.QueryOption {
+	if _, isXPath := s.query(); isXPath {
+		return []chromedp.QueryOption{chromedp.BySearch}
+	}
+	return []chromedp.QueryOption{chromedp.ByQuery}
+}
+
+func (s Selector) waitReady() chromedp.Action {
+	query, _ := s.query()
+	return chromedp.WaitReady(query, s.queryOptions()...)
+}
+
+func (s Selector) sendKeys(keys string) chromedp.Action {
+	query, _ := s.query()
+	return chromedp.SendKeys(query, keys, s.queryOptions()...)
+}
+
+// jsFindElement returns a javascript expression evaluating to the first matching element or null
+func (s Selector) jsFindElement() string {
+	query, isXPath := s.query()
+	queryJSON, _ := json.Marshal(query)
+	if isXPath {
+		return fmt.Sprintf("document.evaluate(%s, document, null, XPathResult.FIRST_ORDERED_NODE_TYPE, null).singleNodeValue", queryJSON)
+	}
+	return fmt.Sprintf("document.querySelector(%s)", queryJSON)
+}
+
+// jsIsVisible returns a javascript expression evaluating to true if a matching element is rendered
+func (s Selector) jsIsVisible() string {
+	return fmt.Sprintf("(el => !!el && el.getClientRects().length > 0)(%s)", s.jsFindElement())
+}
+
+// jsClick returns a javascript expression clicking the first matching element if rendered, it evaluates to true
+// if an element was clicked
+func (s Selector) jsClick() string {
+	return fmt.Sprintf("(el => { if (!el || el.getClientRects().length === 0) { return false; } el.click(); return true; })(%s)", s.jsFindElement())
+}
+
+// xpathLiteral quotes s as an xpath string literal, xpath 1.0 has no escape sequences
+func xpathLiteral(s string) string {
+	if !strings.Contains(s, `"`) {
+		return `"` + s + `"`
+	}
+	if !strings.Contains(s, `'`) {
+		return `'` + s + `'`
+	}
+	parts := strings.Split(s, `"`)
+	for i, part := range parts {
+		parts[i] = `"` + part + `"`
+	}
+	return "concat(" + strings.Join(parts, `, '"', `) + ")"
+}
@@ -0,0 +1,77 @@
+package eseis
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadSelectorProfilePartialOverride(t *testing.T) {
+	defaults, err := DefaultSelectorProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "profile.json")
+	content := `{
+  "version": "custom",
+  "login": {
+    "username": {"attribute": "name", "value": "email", "tag": "input"},
+    "cookieBanner": [{"text": "Accepter", "tag": "button"}]
+  },
+  "reportPage": {"remove": []},
+  "session": {"localStorage": {"token": "{{ .AccessToken }}"}}
+}`
+	if err = os.WriteFile(path, []byte(content), 0660); err != nil {
+		t.Fatal(err)
+	}
+
+	profile, err := LoadSelectorProfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if profile.Version != "custom" {
+		t.Errorf("got version %s, want custom", profile.Version)
+	}
+	wantUsername := Selector{Attribute: "name", Value: "email", Tag: "input"}
+	if profile.Login.Username != wantUsername {
+		t.Errorf("got username selector %+v, want %+v replaced as a whole", profile.Login.Username, wantUsername)
+	}
+	wantCookieBanner := []Selector{{Text: "Accepter", Tag: "button"}}
+	if !reflect.DeepEqual(profile.Login.CookieBanner, wantCookieBanner) {
+		t.Errorf("got cookie banner selectors %+v, want %+v replaced as a whole", profile.Login.CookieBanner, wantCookieBanner)
+	}
+	if len(profile.ReportPage.Remove) != 0 {
+		t.Errorf("got report page remove selectors %+v, want them cleared", profile.ReportPage.Remove)
+	}
+	wantLocalStorage := map[string]string{"token": "{{ .AccessToken }}"}
+	if !reflect.DeepEqual(profile.Session.LocalStorage, wantLocalStorage) {
+		t.Errorf("got local storage %v, want %v replaced as a whole", profile.Session.LocalStorage, wantLocalStorage)
+	}
+
+	// the keys missing from the file keep their default value
+	if profile.Login.Password != defaults.Login.Password {
+		t.Errorf("got password selector %+v, want the default %+v", profile.Login.Password, defaults.Login.Password)
+	}
+	if !reflect.DeepEqual(profile.Login.InvalidCredentials, defaults.Login.InvalidCredentials) {
+		t.Errorf("got invalid credentials selectors %+v, want the default ones", profile.Login.InvalidCredentials)
+	}
+	if !reflect.DeepEqual(profile.ReportPage.WaitFor, defaults.ReportPage.WaitFor) {
+		t.Errorf("got report page wait selectors %+v, want the default ones", profile.ReportPage.WaitFor)
+	}
+	if !reflect.DeepEqual(profile.ForumPage, defaults.ForumPage) {
+		t.Errorf("got forum page selectors %+v, want the default ones", profile.ForumPage)
+	}
+}
+
+func TestLoadSelectorProfileRejectsInvalidSelectors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profile.json")
+	content := `{"login": {"password": {"css": "#password", "text": "Mot de passe"}}}`
+	if err := os.WriteFile(path, []byte(content), 0660); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSelectorProfile(path); err == nil {
+		t.Error("got no error for a selector with both css and text")
+	}
+}
@@ -0,0 +1,176 @@
+package eseis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/chrome"
+	"net/url"
+	"text/template"
+	"time"
+)
+
+// SessionProfile describes where the web UI keeps its session, so that the browser can start it from the API token
+// instead of typing the credentials in the login form. Values are text/template templates executed with a SessionToken.
+type SessionProfile struct {
+	// LocalStorage are the local storage items of the web UI origin
+	LocalStorage map[string]string `json:"localStorage"`
+	// Cookies are the cookies of the web UI url
+	Cookies map[string]string `json:"cookies"`
+}
+
+// SessionToken is the data available to the session templates. The refresh token is left out, the web UI could
+// rotate it and invalidate the one of the API client.
+type SessionToken struct {
+	AccessToken string
+	TokenType   string
+	ExpiresAt   time.Time
+	// ExpiresIn is the number of seconds before ExpiresAt
+	ExpiresIn int64
+}
+
+func (p SessionProfile) isEmpty() bool {
+	return len(p.LocalStorage) == 0 && len(p.Cookies) == 0
+}
+
+func (p SessionProfile) validate() error {
+	_, _, err := p.render(SessionToken{})
+	return err
+}
+
+// render executes the templates of the local storage items and cookies with token
+func (p SessionProfile) render(token SessionToken) (localStorage map[string]string, cookies map[string]string, err error) {
+	if localStorage, err = renderSessionTemplates(p.LocalStorage, token); err != nil {
+		return nil, nil, err
+	}
+	if cookies, err = renderSessionTemplates(p.Cookies, token); err != nil {
+		return nil, nil, err
+	}
+	return localStorage, cookies, nil
+}
+
+func renderSessionTemplates(templates map[string]string, token SessionToken) (map[string]string, error) {
+	values := make(map[string]string, len(templates))
+	for name, text := range templates {
+		tpl, err := template.New(name).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse session template %s: %w", name, err)
+		}
+		buffer := bytes.Buffer{}
+		if err = tpl.Execute(&buffer, token); err != nil {
+			return nil, fmt.Errorf("failed to execute session template %s: %w", name, err)
+		}
+		values[name] = buffer.String()
+	}
+	return values, nil
+}
+
+// tokenLogin starts the web UI session from the API token. It returns an error if the web UI did not accept the
+// session, the local storage items and cookies are then removed so that the login form starts from a clean state.
+func (e *EseisClient) tokenLogin(ctx context.Context, c *chrome.Chrome) error {
+	session := e.selectors.Session
+	if session.isEmpty() {
+		return errors.New("the selector profile has no session")
+	}
+	token, err := e.getToken(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get API token: %w", err)
+	}
+	localStorage, cookies, err := session.render(SessionToken{
+		AccessToken: token.accessToken,
+		TokenType:   "Bearer",
+		ExpiresAt:   token.expiresAt,
+		ExpiresIn:   int64(time.Until(token.expiresAt).Seconds()),
+	})
+	if err != nil {
+		return err
+	}
+	webURL, err := url.Parse(e.config.BaseWebURL)
+	if err != nil {
+		return fmt.Errorf("invalid web UI url %s: %w", e.config.BaseWebURL, err)
+	}
+	origin := webURL.Scheme + "://" + webURL.Host
+
+	state := loginStateTimedOut
+	checks := append(e.loginChecks(e.selectors.Login.LoggedIn), loginCheck{[]Selector{e.selectors.Login.Username}, loginStateLoginForm})
+	var scriptID page.ScriptIdentifier
+	tasks := chromedp.Tasks{
+		chromedp.EmulateViewport(799, 799),
+		// set the local storage before the scripts of the web UI read it
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			scriptID, err = page.AddScriptToEvaluateOnNewDocument(setLocalStorageScript(origin, localStorage)).Do(ctx)
+			return err
+		}),
+		setCookiesAction(e.config.BaseWebURL, cookies, token.expiresAt),
+		chromedp.Navigate(e.config.BaseWebURL),
+		e.waitLoginStateAction(checks, &state),
+	}
+	err = c.RunTasks(ctx, tasks)
+	if scriptID != "" {
+		// the web UI manages its session from now on, later pages must not restore this token
+		if removeErr := c.RunTasks(ctx, chromedp.Tasks{page.RemoveScriptToEvaluateOnNewDocument(scriptID)}); removeErr != nil && err == nil {
+			err = fmt.Errorf("failed to remove the session script: %w", removeErr)
+		}
+	}
+	if err == nil && state == loginStateReady {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err == nil {
+		err = fmt.Errorf("element %s not found", e.selectors.Login.LoggedIn)
+	}
+	if clearErr := c.RunTasks(ctx, clearSessionActions(e.config.BaseWebURL, origin, localStorage, cookies)); clearErr != nil {
+		err = fmt.Errorf("%w, then failed to clear the session: %s", err, clearErr)
+	}
+	return fmt.Errorf("web UI did not accept the API token session: %w", err)
+}
+
+// setLocalStorageScript returns a script setting the local storage items on the documents of origin
+func setLocalStorageScript(origin string, items map[string]string) string {
+	originJSON, _ := json.Marshal(origin)
+	itemsJSON, _ := json.Marshal(items)
+	return fmt.Sprintf(`(items => {
+	if (location.origin !== %s) { return; }
+	for (const [key, value] of Object.entries(items)) { localStorage.setItem(key, value); }
+})(%s)`, originJSON, itemsJSON)
+}
+
+func setCookiesAction(webURL string, cookies map[string]string, expiresAt time.Time) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		expires := cdp.TimeSinceEpoch(expiresAt)
+		for name, value := range cookies {
+			if err := network.SetCookie(name, value).WithURL(webURL).WithExpires(&expires).Do(ctx); err != nil {
+				return fmt.Errorf("failed to set cookie %s: %w", name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// clearSessionActions remove the local storage items and the cookies set from the token
+func clearSessionActions(webURL string, origin string, localStorage map[string]string, cookies map[string]string) chromedp.Tasks {
+	var tasks chromedp.Tasks
+	if len(localStorage) > 0 {
+		keys := make([]string, 0, len(localStorage))
+		for key := range localStorage {
+			keys = append(keys, key)
+		}
+		originJSON, _ := json.Marshal(origin)
+		keysJSON, _ := json.Marshal(keys)
+		script := fmt.Sprintf(`location.origin === %s && %s.forEach(key => localStorage.removeItem(key))`, originJSON, keysJSON)
+		tasks = append(tasks, chromedp.Evaluate(script, nil))
+	}
+	for name := range cookies {
+		tasks = append(tasks, network.DeleteCookies(name).WithURL(webURL))
+	}
+	return tasks
+}
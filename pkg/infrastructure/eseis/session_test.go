@@ -0,0 +1,82 @@
+package eseis
+
+import (
+	"context"
+	"fmt"
+	"github.com/chromedp/chromedp"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// sessionTestPage is a web UI showing the balance when the local storage holds the accepted token, and a login form
+// otherwise. Submitting the form shows the balance and records its use in the session storage.
+const sessionTestPage = `<html><body>
+<div id="form" style="display: none">
+<input id="login-username" onkeydown="if (event.key === 'Enter') { document.getElementById('login-password').style.display = 'inline'; }">
+<input id="login-password" style="display: none" onkeydown="if (event.key === 'Enter') { sessionStorage.setItem('form', 'used'); document.getElementById('balance').style.display = 'block'; }">
+</div>
+<p id="balance" style="display: none">Balance</p>
+<script>
+if (localStorage.getItem('eseis-token') === %q) {
+	document.getElementById('balance').style.display = 'block';
+} else {
+	document.getElementById('form').style.display = 'block';
+}
+</script>
+</body></html>`
+
+func TestWebUIBrowserFallsBackToTheLoginForm(t *testing.T) {
+	tests := []struct {
+		name          string
+		acceptedToken string
+		wantFormLogin bool
+	}{
+		// the token of tokenTransport
+		{"accepted session", "token", false},
+		{"rejected session", "expired", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, sessionTestPage, test.acceptedToken)
+			}))
+			defer server.Close()
+
+			profile, err := DefaultSelectorProfile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			profile.Login.LoggedIn = Selector{CSS: "#balance"}
+			profile.Session = SessionProfile{LocalStorage: map[string]string{"eseis-token": "{{ .AccessToken }}"}}
+			var requests []*http.Request
+			client := newBrowserTestClient(t, WithSelectorProfile(*profile), WithTransport(tokenTransport(&requests)))
+			client.config.BaseWebURL = server.URL
+			client.config.DiagnosticsDir = t.TempDir()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			chromeSession, err := client.webUIBrowser(ctx)
+			if err != nil {
+				t.Fatalf("got error %v, want a logged in browser", err)
+			}
+
+			var formLogin, tokenCleared bool
+			err = chromeSession.RunTasks(ctx, chromedp.Tasks{
+				chromedp.Evaluate(`sessionStorage.getItem('form') === 'used'`, &formLogin),
+				chromedp.Evaluate(`localStorage.getItem('eseis-token') === null`, &tokenCleared),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if formLogin != test.wantFormLogin {
+				t.Errorf("got login form used %t, want %t", formLogin, test.wantFormLogin)
+			}
+			// a rejected session is removed before the login form
+			if tokenCleared != test.wantFormLogin {
+				t.Errorf("got session token cleared %t, want %t", tokenCleared, test.wantFormLogin)
+			}
+		})
+	}
+}
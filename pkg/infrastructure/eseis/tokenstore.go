@@ -0,0 +1,106 @@
+package eseis
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/utils"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Token is an oauth token of the Eseis API as persisted by a TokenStore
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// TokenStore persists the oauth token between runs
+type TokenStore interface {
+	// Load returns the stored token or nil if there is none
+	Load() (*Token, error)
+	Save(token Token) error
+}
+
+// FileTokenStore is a TokenStore persisting the token in a file encrypted with AES-GCM
+type FileTokenStore struct {
+	path string
+	aead cipher.AEAD
+}
+
+// NewFileTokenStore creates a FileTokenStore writing to path, encrypted with a key derived from secret
+func NewFileTokenStore(path string, secret string) (*FileTokenStore, error) {
+	if secret == "" {
+		return nil, errors.New("token cache encryption key is empty")
+	}
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token cache cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token cache cipher: %w", err)
+	}
+	return &FileTokenStore{path: path, aead: aead}, nil
+}
+
+// defaultTokenCachePath returns a token cache file under the user cache dir ($XDG_CACHE_HOME on linux), one per account
+func defaultTokenCachePath(config *Config) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find user cache dir: %w", err)
+	}
+	account := sha256.Sum256([]byte(config.BaseURL + "\n" + config.Username))
+	return filepath.Join(cacheDir, "eseis-scrapper", "token-"+hex.EncodeToString(account[:8])), nil
+}
+
+func (s *FileTokenStore) Load() (*Token, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token cache %s: %w", s.path, err)
+	}
+	nonceSize := s.aead.NonceSize()
+	if len(data) < nonceSize {
+		return nil, fmt.Errorf("token cache %s is corrupted", s.path)
+	}
+	plaintext, err := s.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt token cache %s: %w", s.path, err)
+	}
+	token := &Token{}
+	if err = json.Unmarshal(plaintext, token); err != nil {
+		return nil, fmt.Errorf("failed to decode token cache %s: %w", s.path, err)
+	}
+	return token, nil
+}
+
+func (s *FileTokenStore) Save(token Token) error {
+	plaintext, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to encode token cache: %w", err)
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate token cache nonce: %w", err)
+	}
+	data := s.aead.Seal(nonce, nonce, plaintext, nil)
+
+	if err = os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create token cache dir: %w", err)
+	}
+	if err = utils.WriteBytesAtomic(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write token cache: %w", err)
+	}
+	return nil
+}
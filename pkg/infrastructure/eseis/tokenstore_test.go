@@ -0,0 +1,86 @@
+package eseis
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileTokenStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "token")
+	store, err := NewFileTokenStore(path, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := store.Load()
+	if err != nil || token != nil {
+		t.Fatalf("got token %+v and error %v before any save, want none", token, err)
+	}
+
+	saved := Token{AccessToken: "access", RefreshToken: "refresh", ExpiresAt: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)}
+	if err = store.Save(saved); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("access")) || bytes.Contains(data, []byte("refresh")) {
+		t.Errorf("token cache contains the token in clear: %q", data)
+	}
+
+	token, err = store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == nil || token.AccessToken != saved.AccessToken || token.RefreshToken != saved.RefreshToken || !token.ExpiresAt.Equal(saved.ExpiresAt) {
+		t.Errorf("got token %+v, want %+v", token, saved)
+	}
+}
+
+func TestFileTokenStoreLoadFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		data   []byte
+	}{
+		{"wrong key", "other secret", nil},
+		{"truncated", "secret", []byte("short")},
+		{"tampered", "secret", append(bytes.Repeat([]byte{0}, 12), []byte("not encrypted with the key")...)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "token")
+			store, err := NewFileTokenStore(path, "secret")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = store.Save(Token{AccessToken: "access"}); err != nil {
+				t.Fatal(err)
+			}
+			if test.data != nil {
+				if err = os.WriteFile(path, test.data, 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			store, err = NewFileTokenStore(path, test.secret)
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, err := store.Load()
+			if err == nil || token != nil {
+				t.Errorf("got token %+v and error %v, want an error", token, err)
+			}
+		})
+	}
+}
+
+func TestNewFileTokenStoreRequiresASecret(t *testing.T) {
+	if _, err := NewFileTokenStore(filepath.Join(t.TempDir(), "token"), ""); err == nil {
+		t.Error("got no error for an empty secret")
+	}
+}
@@ -0,0 +1,166 @@
+package eseis
+
+import (
+	"context"
+	"fmt"
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/utils"
+	"io"
+	"mime"
+	"time"
+)
+
+// fileExtensions are the file extensions of the known attachment content types.
+// Changing them renames the files of previous exports, which are then downloaded again.
+var fileExtensions = map[string]string{
+	"application/pdf":           ".pdf",
+	"image/jpeg":                ".jpg",
+	"image/jpg":                 ".jpg",
+	"image/pjpeg":               ".jpg",
+	"image/png":                 ".png",
+	"image/gif":                 ".gif",
+	"image/webp":                ".webp",
+	"multipart/related":         ".mhtml",
+	"message/rfc822":            ".mhtml",
+	"application/x-mimearchive": ".mhtml",
+}
+
+// fileExtension returns the file extension of a content type, looked up in the mime types of the system when unknown,
+// or an empty string
+func fileExtension(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	if extension, ok := fileExtensions[mediaType]; ok {
+		return extension
+	}
+	extensions, err := mime.ExtensionsByType(mediaType)
+	if err != nil || len(extensions) == 0 {
+		return ""
+	}
+	return extensions[0]
+}
+
+// AttachmentDownloader downloads attachments, it is implemented by EseisClient
+type AttachmentDownloader interface {
+	DownloadAttachment(ctx context.Context, url string, w io.Writer) (int64, error)
+}
+
+// DocumentDownloader downloads documents, it is implemented by EseisClient
+type DocumentDownloader interface {
+	DownloadDocument(ctx context.Context, uuid string, w io.Writer) (int64, error)
+}
+
+// attachmentResponse is the attachment payload shared by reports, report events, forum topics and posts
+type attachmentResponse struct {
+	ID                int       `json:"id"`
+	UUID              string    `json:"uuid"`
+	SourceFileName    string    `json:"source_file_name"`
+	SourceContentType string    `json:"source_content_type"`
+	SourceFileSize    int       `json:"source_file_size"`
+	FileURL           string    `json:"file_url"`
+	SourceUpdatedAt   time.Time `json:"source_updated_at"`
+}
+
+// Attachment is a file attached to a report, a report event, a forum topic, a post or an account entry
+type Attachment struct {
+	ID          int
+	UUID        string
+	FileName    string
+	ContentType string
+	// Size is the size of the file in bytes, 0 if unknown
+	Size      int
+	FileURL   string
+	UpdatedAt time.Time
+}
+
+// Extension returns the file extension matching the content type, or an empty string for unknown types
+func (a Attachment) Extension() string {
+	return fileExtension(a.ContentType)
+}
+
+// CleanFileName returns the name of the exported file, made unique by the attachment id
+func (a Attachment) CleanFileName() string {
+	return utils.SanitizePath(fmt.Sprintf("%s_%d%s", a.FileName, a.ID, a.Extension()))
+}
+
+// Download streams the attachment to w and returns the number of bytes written
+func (a Attachment) Download(ctx context.Context, downloader AttachmentDownloader, w io.Writer) (int64, error) {
+	return downloader.DownloadAttachment(ctx, a.FileURL, w)
+}
+
+func (a attachmentResponse) toAttachment() Attachment {
+	return Attachment{
+		ID:          a.ID,
+		UUID:        a.UUID,
+		FileName:    a.SourceFileName,
+		ContentType: a.SourceContentType,
+		Size:        a.SourceFileSize,
+		FileURL:     a.FileURL,
+		UpdatedAt:   a.SourceUpdatedAt,
+	}
+}
+
+func toAttachments(responses []attachmentResponse) []Attachment {
+	attachments := make([]Attachment, len(responses))
+	for i, response := range responses {
+		attachments[i] = response.toAttachment()
+	}
+	return attachments
+}
+
+// authorResponse is the author payload shared by reports, report events, forum topics and posts
+type authorResponse struct {
+	ID               int    `json:"id"`
+	RealName         string `json:"real_name"`
+	DisplayPlaceRole string `json:"display_place_role"`
+	DisplayName      string `json:"display_name"`
+	AvatarURL        string `json:"avatar_url"`
+	SergicPartner    bool   `json:"sergic_partner"`
+}
+
+// Author is the author of a report, a report event, a forum topic or a post
+type Author struct {
+	ID               int
+	RealName         string
+	DisplayPlaceRole string
+	DisplayName      string
+	AvatarURL        string
+	SergicPartner    bool
+}
+
+func (a authorResponse) toAuthor() Author {
+	return Author{
+		ID:               a.ID,
+		RealName:         a.RealName,
+		DisplayPlaceRole: a.DisplayPlaceRole,
+		DisplayName:      a.DisplayName,
+		AvatarURL:        a.AvatarURL,
+		SergicPartner:    a.SergicPartner,
+	}
+}
+
+// DocumentRef references a pdf document downloadable by uuid, it is shared by contract, coownership, maintenance
+// contract and account entry documents
+type DocumentRef struct {
+	ID          int
+	UUID        string
+	DisplayName string
+	FileURL     string
+	UpdatedAt   time.Time
+}
+
+// Extension returns the file extension of the document
+func (d DocumentRef) Extension() string {
+	return fileExtensions["application/pdf"]
+}
+
+// CleanFileName returns the name of the exported file
+func (d DocumentRef) CleanFileName() string {
+	return utils.SanitizePath(d.DisplayName + d.Extension())
+}
+
+// Download streams the document to w and returns the number of bytes written
+func (d DocumentRef) Download(ctx context.Context, downloader DocumentDownloader, w io.Writer) (int64, error) {
+	return downloader.DownloadDocument(ctx, d.UUID, w)
+}
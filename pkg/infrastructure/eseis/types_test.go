@@ -0,0 +1,39 @@
+package eseis
+
+import (
+	"mime"
+	"testing"
+)
+
+func TestAttachmentCleanFileName(t *testing.T) {
+	if err := mime.AddExtensionType(".heic", "image/heic"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		contentType string
+		want        string
+	}{
+		{"application/pdf", "photo_7.pdf"},
+		{"image/jpeg", "photo_7.jpg"},
+		{"image/jpg", "photo_7.jpg"},
+		{"image/png", "photo_7.png"},
+		{"IMAGE/PNG", "photo_7.png"},
+		{"image/gif", "photo_7.gif"},
+		{"image/webp", "photo_7.webp"},
+		{"multipart/related", "photo_7.mhtml"},
+		{"image/png; charset=binary", "photo_7.png"},
+		// unknown types fall back to the mime types of the system
+		{"image/heic", "photo_7.heic"},
+		{"application/x-eseis-unknown", "photo_7"},
+		{"", "photo_7"},
+		{"not a content type", "photo_7"},
+	}
+	for _, test := range tests {
+		t.Run(test.contentType, func(t *testing.T) {
+			attachment := Attachment{ID: 7, FileName: "photo", ContentType: test.contentType}
+			if got := attachment.CleanFileName(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package render
+
+import (
+	"embed"
+	"fmt"
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/eseis"
+	"html/template"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+//go:embed templates/*.html.tmpl
+var templates embed.FS
+
+const (
+	reportTemplate     = "report.html.tmpl"
+	forumTopicTemplate = "forum_topic.html.tmpl"
+)
+
+// ReportPage is the data rendered for a report
+type ReportPage struct {
+	Summary    eseis.ReportSummary
+	Report     eseis.Report
+	RenderedAt time.Time
+}
+
+// URL returns the address of the report in the web UI
+func (p ReportPage) URL() string {
+	return p.Summary.URL
+}
+
+// ForumTopicPage is the data rendered for a forum topic
+type ForumTopicPage struct {
+	Topic      eseis.ForumTopic
+	Posts      []eseis.TopicPost
+	RenderedAt time.Time
+}
+
+// URL returns the address of the forum topic in the web UI
+func (p ForumTopicPage) URL() string {
+	return p.Topic.URL
+}
+
+// Renderer renders standalone html pages of the reports and forum topics from the API data, without a browser.
+// The attachments are linked relative to the page, they must be exported to the same dir.
+type Renderer struct {
+	templates *template.Template
+}
+
+// NewRenderer creates a Renderer with the embedded templates
+func NewRenderer() (*Renderer, error) {
+	tpl, err := template.New("").Funcs(templateFuncs).ParseFS(templates, "templates/*.html.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse render templates: %w", err)
+	}
+	return &Renderer{templates: tpl}, nil
+}
+
+// RenderReport writes the html page of a report to w, the events are rendered from the oldest to the newest
+func (r *Renderer) RenderReport(w io.Writer, page ReportPage) error {
+	page.Report.ReportEvents = append([]eseis.ReportEvent(nil), page.Report.ReportEvents...)
+	sort.SliceStable(page.Report.ReportEvents, func(i, j int) bool {
+		return page.Report.ReportEvents[i].CreatedAt.Before(page.Report.ReportEvents[j].CreatedAt)
+	})
+	if err := r.templates.ExecuteTemplate(w, reportTemplate, page); err != nil {
+		return fmt.Errorf("failed to render report %d: %w", page.Report.ID, err)
+	}
+	return nil
+}
+
+// RenderForumTopic writes the html page of a forum topic to w, the posts are rendered from the oldest to the newest
+func (r *Renderer) RenderForumTopic(w io.Writer, page ForumTopicPage) error {
+	page.Posts = append([]eseis.TopicPost(nil), page.Posts...)
+	sort.SliceStable(page.Posts, func(i, j int) bool {
+		return page.Posts[i].CreatedAt.Before(page.Posts[j].CreatedAt)
+	})
+	if err := r.templates.ExecuteTemplate(w, forumTopicTemplate, page); err != nil {
+		return fmt.Errorf("failed to render forum topic %d: %w", page.Topic.ID, err)
+	}
+	return nil
+}
+
+var templateFuncs = template.FuncMap{
+	"relativeURL": relativeURL,
+	"isImage":     isImage,
+	"formatTime":  formatTime,
+	"formatSize":  formatSize,
+}
+
+// relativeURL returns the url of a file exported next to the page, escaping the characters of the file name
+func relativeURL(fileName string) template.URL {
+	return template.URL((&url.URL{Path: "./" + fileName}).String())
+}
+
+// isImage tells whether the attachment can be displayed inline
+func isImage(attachment eseis.Attachment) bool {
+	return strings.HasPrefix(attachment.ContentType, "image/")
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func formatSize(size int) string {
+	switch {
+	case size <= 0:
+		return ""
+	case size < 1<<10:
+		return fmt.Sprintf("%d B", size)
+	case size < 1<<20:
+		return fmt.Sprintf("%.1f KiB", float64(size)/(1<<10))
+	}
+	return fmt.Sprintf("%.1f MiB", float64(size)/(1<<20))
+}
@@ -0,0 +1,158 @@
+package render
+
+import (
+	"bytes"
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/eseis"
+	"html/template"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRelativeURL(t *testing.T) {
+	tests := []struct {
+		fileName string
+		want     template.URL
+	}{
+		{"report.pdf", "./report.pdf"},
+		{"photo du hall_12.jpg", "./photo%20du%20hall_12.jpg"},
+		{"devis #3?_4.pdf", "./devis%20%233%3F_4.pdf"},
+		{"facture:mars_5.pdf", "./facture:mars_5.pdf"},
+		{"été_6.png", "./%C3%A9t%C3%A9_6.png"},
+	}
+	for _, test := range tests {
+		t.Run(test.fileName, func(t *testing.T) {
+			if got := relativeURL(test.fileName); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		size int
+		want string
+	}{
+		{-1, ""},
+		{0, ""},
+		{1, "1 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{1<<20 - 1, "1024.0 KiB"},
+		{1 << 20, "1.0 MiB"},
+		{5<<20 + 1<<19, "5.5 MiB"},
+	}
+	for _, test := range tests {
+		if got := formatSize(test.size); got != test.want {
+			t.Errorf("got %q for %d bytes, want %q", got, test.size, test.want)
+		}
+	}
+}
+
+func TestRenderReport(t *testing.T) {
+	renderer, err := NewRenderer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := time.Date(2023, 3, 1, 10, 0, 0, 0, time.UTC)
+	page := ReportPage{
+		Summary: eseis.ReportSummary{
+			Author: eseis.Author{DisplayName: "Jean Dupont", DisplayPlaceRole: "Copropriétaire"},
+			URL:    "https://example.com/reports/7",
+		},
+		Report: eseis.Report{
+			ID:          7,
+			DisplayName: "Fuite <parking>",
+			Description: "<script>alert(1)</script>",
+			State:       "open",
+			CreatedAt:   created,
+			Attachments: []eseis.Attachment{
+				{ID: 1, FileName: "photo 1.jpg", ContentType: "image/jpeg", Size: 2048},
+				{ID: 2, FileName: "devis", ContentType: "application/pdf"},
+			},
+			ReportEvents: []eseis.ReportEvent{
+				{ID: 2, Description: "second event", CreatedAt: created.Add(2 * time.Hour)},
+				{ID: 1, Description: "first event", CreatedAt: created.Add(time.Hour)},
+			},
+		},
+		RenderedAt: created,
+	}
+	events := append([]eseis.ReportEvent(nil), page.Report.ReportEvents...)
+
+	var buffer bytes.Buffer
+	if err = renderer.RenderReport(&buffer, page); err != nil {
+		t.Fatal(err)
+	}
+	html := buffer.String()
+
+	for _, want := range []string{
+		"<title>Fuite &lt;parking&gt;</title>",
+		"&lt;script&gt;alert(1)&lt;/script&gt;",
+		`<span class="author">Jean Dupont</span> <span class="meta">Copropriétaire</span>`,
+		`<a href="./photo%201.jpg_1.jpg">photo 1.jpg</a> <span class="meta">2.0 KiB</span>`,
+		`<img src="./photo%201.jpg_1.jpg" alt="photo 1.jpg">`,
+		`<a href="./devis_2.pdf">devis</a>`,
+		`Source: <a href="https://example.com/reports/7">`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("rendered report does not contain %s:\n%s", want, html)
+		}
+	}
+	if strings.Contains(html, "<script>") {
+		t.Error("rendered report contains the unescaped description")
+	}
+	if strings.Count(html, "<img") != 1 {
+		t.Errorf("got %d images, want only the image attachment inline", strings.Count(html, "<img"))
+	}
+	if first, second := strings.Index(html, "first event"), strings.Index(html, "second event"); first < 0 || first > second {
+		t.Error("rendered report events are not sorted from the oldest to the newest")
+	}
+	if page.Report.ReportEvents[0].ID != events[0].ID || page.Report.ReportEvents[1].ID != events[1].ID {
+		t.Error("rendering the report sorted the events of the caller")
+	}
+}
+
+func TestRenderForumTopic(t *testing.T) {
+	renderer, err := NewRenderer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := time.Date(2023, 3, 1, 10, 0, 0, 0, time.UTC)
+	page := ForumTopicPage{
+		Topic: eseis.ForumTopic{
+			ID:          3,
+			DisplayName: "Local vélo",
+			Description: "Qui a la clé ?",
+			CreatedAt:   created,
+			Author:      eseis.Author{DisplayName: "Marie"},
+		},
+		Posts: []eseis.TopicPost{
+			{ID: 2, Body: "second post", CreatedAt: created.Add(2 * time.Hour), Author: eseis.Author{DisplayName: "Paul"}},
+			{ID: 1, Body: "first post", CreatedAt: created.Add(time.Hour), Author: eseis.Author{DisplayName: "Marie"}},
+		},
+		RenderedAt: created,
+	}
+
+	var buffer bytes.Buffer
+	if err = renderer.RenderForumTopic(&buffer, page); err != nil {
+		t.Fatal(err)
+	}
+	html := buffer.String()
+
+	for _, want := range []string{"<title>Local vélo</title>", "Qui a la clé ?", `<span class="author">Paul</span>`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("rendered forum topic does not contain %s:\n%s", want, html)
+		}
+	}
+	if strings.Contains(html, "Source:") {
+		t.Error("rendered forum topic links a source without a topic url")
+	}
+	if strings.Contains(html, `class="attachments"`) {
+		t.Error("rendered forum topic lists attachments without any")
+	}
+	if first, second := strings.Index(html, "first post"), strings.Index(html, "second post"); first < 0 || first > second {
+		t.Error("rendered forum topic posts are not sorted from the oldest to the newest")
+	}
+}
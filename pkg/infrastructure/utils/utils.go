@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"fmt"
 	"github.com/sirupsen/logrus"
 	"os"
 	"path/filepath"
@@ -9,15 +10,22 @@ import (
 
 func MustBeNilErr(err error, message string, args ...interface{}) {
 	if err != nil {
-		logrus.Fatalf(message+": %s", err, args)
+		logrus.Fatalf(message+": %s", append(args, err)...)
 	}
 }
 
 func MkDirFatal(path string) {
-	err := os.MkdirAll(path, 0770)
+	err := MkDir(path)
 	MustBeNilErr(err, "failed to create dir %s", path)
 }
 
+func MkDir(path string) error {
+	if err := os.MkdirAll(path, 0770); err != nil {
+		return fmt.Errorf("failed to create dir %s: %w", path, err)
+	}
+	return nil
+}
+
 func JoinFilePath(elements ...string) string {
 	return filepath.Join(elements...)
 }
@@ -26,3 +34,43 @@ func SanitizePath(filePath string) string {
 	filePath = strings.ReplaceAll(filePath, "/", "_")
 	return strings.Trim(filePath, " ")
 }
+
+// WriteFileAtomic writes a file through write into a temporary file of the same directory which is synced and
+// renamed to path only if write succeeded, so that path never holds a partially written file
+func WriteFileAtomic(path string, perm os.FileMode, write func(f *os.File) error) (err error) {
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %s: %w", path, err)
+	}
+	defer func() {
+		if err != nil {
+			tmpFile.Close()
+			os.Remove(tmpFile.Name())
+		}
+	}()
+
+	if err = write(tmpFile); err != nil {
+		return err
+	}
+	if err = tmpFile.Chmod(perm); err != nil {
+		return fmt.Errorf("failed to chmod temporary file for %s: %w", path, err)
+	}
+	if err = tmpFile.Sync(); err != nil {
+		return fmt.Errorf("failed to sync temporary file for %s: %w", path, err)
+	}
+	if err = tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file for %s: %w", path, err)
+	}
+	if err = os.Rename(tmpFile.Name(), path); err != nil {
+		return fmt.Errorf("failed to rename temporary file to %s: %w", path, err)
+	}
+	return nil
+}
+
+// WriteBytesAtomic is WriteFileAtomic for content already in memory
+func WriteBytesAtomic(path string, content []byte, perm os.FileMode) error {
+	return WriteFileAtomic(path, perm, func(f *os.File) error {
+		_, err := f.Write(content)
+		return err
+	})
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	writeErr := errors.New("write failed")
+	tests := []struct {
+		name     string
+		existing string
+		write    func(f *os.File) error
+		want     string
+		wantErr  error
+	}{
+		{"new file", "", func(f *os.File) error {
+			_, err := io.WriteString(f, "content")
+			return err
+		}, "content", nil},
+		{"replaced file", "previous", func(f *os.File) error {
+			_, err := io.WriteString(f, "content")
+			return err
+		}, "content", nil},
+		{"failed new file", "", func(f *os.File) error {
+			_, _ = io.WriteString(f, "partial")
+			return writeErr
+		}, "", writeErr},
+		{"failed replacement", "previous", func(f *os.File) error {
+			_, _ = io.WriteString(f, "partial")
+			return writeErr
+		}, "previous", writeErr},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "file.pdf")
+			if test.existing != "" {
+				if err := os.WriteFile(path, []byte(test.existing), 0660); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := WriteFileAtomic(path, 0640, test.write)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+
+			data, err := os.ReadFile(path)
+			if test.want == "" {
+				if !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("got file %q after a failed write, want no file", data)
+				}
+			} else if string(data) != test.want {
+				t.Errorf("got content %q, want %q", data, test.want)
+			}
+			if test.wantErr == nil {
+				info, err := os.Stat(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if info.Mode().Perm() != 0640 {
+					t.Errorf("got mode %s, want %s", info.Mode().Perm(), os.FileMode(0640))
+				}
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, entry := range entries {
+				if entry.Name() != "file.pdf" {
+					t.Errorf("temporary file %s left in the directory", entry.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestWriteBytesAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "info.json")
+	if err := WriteBytesAtomic(path, []byte("{}"), 0660); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "{}" {
+		t.Errorf("got content %q and error %v, want {}", data, err)
+	}
+}
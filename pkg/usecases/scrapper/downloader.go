@@ -1 +1,120 @@
 package scrapper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/eseis"
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/utils"
+	"github.com/sirupsen/logrus"
+	"os"
+	"strconv"
+	"time"
+)
+
+// submitDocument queues the export of a document to folderPath on the download pool
+func (x *Exporter) submitDocument(contract eseis.Contract, section string, document eseis.DocumentRef, folderPath string) error {
+	return x.submitDownload(contract, section, document.UUID, x.api.DocumentURL(document.UUID), "document "+document.DisplayName, func(ctx context.Context) error {
+		return x.exportDocument(ctx, section, document, folderPath)
+	})
+}
+
+// submitAttachments queues the export of attachments to folderPath on the download pool
+func (x *Exporter) submitAttachments(contract eseis.Contract, section string, attachments []eseis.Attachment, folderPath string) error {
+	for _, attachment := range attachments {
+		attachment := attachment
+		err := x.submitDownload(contract, section, strconv.Itoa(attachment.ID), attachment.FileURL, "attachment "+attachment.FileName, func(ctx context.Context) error {
+			return x.exportAttachment(ctx, section, attachment, folderPath)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportDocument downloads the document to folderPath unless the manifest says it is already up to date
+func (x *Exporter) exportDocument(ctx context.Context, section string, document eseis.DocumentRef, folderPath string) error {
+	logrus.Infof("Exporting document %s:%s to folder %s", document.UUID, document.DisplayName, folderPath)
+
+	documentFilePath := utils.JoinFilePath(folderPath, document.CleanFileName())
+
+	upToDate, err := x.isAlreadyExported(section, document.UUID, document.UpdatedAt, documentFilePath)
+	if err != nil {
+		return err
+	}
+	if upToDate {
+		logrus.Infof("document %s:%s already downloaded", document.UUID, document.DisplayName)
+		return nil
+	}
+
+	// write through a temporary file so that a failed download never leaves a file considered up to date by the next run
+	err = utils.WriteFileAtomic(documentFilePath, 0660, func(documentFile *os.File) error {
+		_, err := document.Download(ctx, x.api, documentFile)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to download document for uuid %s to path %s: %w", document.UUID, documentFilePath, err)
+	}
+	if err = x.manifest.RecordFile(section, document.UUID, document.UpdatedAt, documentFilePath); err != nil {
+		return fmt.Errorf("failed to record document %s in manifest: %w", document.UUID, err)
+	}
+	return nil
+}
+
+// exportAttachment downloads the attachment to folderPath unless the manifest says it is already up to date
+func (x *Exporter) exportAttachment(ctx context.Context, section string, attachment eseis.Attachment, folderPath string) error {
+	logrus.Infof("Exporting attachment %s:%s to folder %s", attachment.FileURL, attachment.FileName, folderPath)
+
+	attachmentFilePath := utils.JoinFilePath(folderPath, attachment.CleanFileName())
+
+	attachmentRemoteID := strconv.Itoa(attachment.ID)
+	upToDate, err := x.isAlreadyExported(section, attachmentRemoteID, attachment.UpdatedAt, attachmentFilePath)
+	if err != nil {
+		return err
+	}
+	if upToDate {
+		logrus.Infof("attachment %s:%s already downloaded", attachment.FileURL, attachment.FileName)
+		return nil
+	}
+
+	err = utils.WriteFileAtomic(attachmentFilePath, 0660, func(attachmentFile *os.File) error {
+		_, err := attachment.Download(ctx, x.api, attachmentFile)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to download attachment for url %s to path %s: %w", attachment.FileURL, attachmentFilePath, err)
+	}
+	if err = x.manifest.RecordFile(section, attachmentRemoteID, attachment.UpdatedAt, attachmentFilePath); err != nil {
+		return fmt.Errorf("failed to record attachment %d in manifest: %w", attachment.ID, err)
+	}
+	return nil
+}
+
+// ExportInfoFile writes content as an indented json metadata file
+func (x *Exporter) ExportInfoFile(content any, infoFilePath string) error {
+	contentJson, err := json.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize info file for %+v: %w", content, err)
+	}
+	if err = utils.WriteBytesAtomic(infoFilePath, contentJson, 0660); err != nil {
+		return fmt.Errorf("failed to write info file %s: %w", infoFilePath, err)
+	}
+	return nil
+}
+
+// isAlreadyExported checks in the manifest if the item was already exported to path at its remote update date.
+// Existing files without an entry are adopted if they are more recent than the remote update date.
+func (x *Exporter) isAlreadyExported(section string, remoteID string, updatedAt time.Time, path string) (bool, error) {
+	if x.manifest.IsUpToDate(section, remoteID, updatedAt, path) {
+		return true, nil
+	}
+	adopted, err := x.manifest.AdoptLegacyFile(section, remoteID, updatedAt, path)
+	if err != nil {
+		return false, fmt.Errorf("failed to record existing file %s in manifest: %w", path, err)
+	}
+	if adopted {
+		logrus.Debugf("%s is not in the manifest but more recent than its remote update, adopting it", path)
+	}
+	return adopted, nil
+}
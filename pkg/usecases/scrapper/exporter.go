@@ -0,0 +1,255 @@
+package scrapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/eseis"
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/render"
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/utils"
+	"github.com/sirupsen/logrus"
+	"io"
+	"sync"
+	"time"
+)
+
+const sergicOffer = "ESE"
+
+// EseisAPI is the subset of the Eseis client used by the exporter
+type EseisAPI interface {
+	GetContracts(ctx context.Context, sergicOffer string) ([]eseis.Contract, error)
+	IterContractFolders(contractID int, opts ...eseis.PagerOption) *eseis.Pager[eseis.ContractFolder]
+	IterContractDocuments(contractID int, folderID int, opts ...eseis.PagerOption) *eseis.Pager[eseis.ContractDocument]
+	IterCoownershipFolders(placeID int, opts ...eseis.PagerOption) *eseis.Pager[eseis.CoownershipFolder]
+	IterCoownershipDocuments(placeID int, folderID int, opts ...eseis.PagerOption) *eseis.Pager[eseis.CoownershipDocument]
+	GetMaintenanceContractCategories(ctx context.Context, placeID int) ([]eseis.MaintenanceContractCategory, error)
+	GetMaintenanceContractDetails(ctx context.Context, maintenanceContractID int) (eseis.MaintenanceContractDetails, error)
+	IterReportSummaries(placeID int, opts ...eseis.PagerOption) *eseis.Pager[eseis.ReportSummary]
+	GetReport(ctx context.Context, reportID int) (eseis.Report, error)
+	CreateReportScreenshot(ctx context.Context, report eseis.ReportSummary, outDir string) error
+	IterForumTopics(placeID int, opts ...eseis.PagerOption) *eseis.Pager[eseis.ForumTopic]
+	AllTopicPosts(ctx context.Context, placeID int, topicID int, opts ...eseis.PagerOption) ([]eseis.TopicPost, error)
+	CreateForumTopicScreenshot(ctx context.Context, forumTopic eseis.ForumTopic, outDir string) error
+	GetFiscalYears(ctx context.Context, placeID int) ([]eseis.FiscalYear, error)
+	GetBudgets(ctx context.Context, placeID int, fiscalYearID int) ([]eseis.Budget, error)
+	GetAccountPlaceEntries(ctx context.Context, budgetID int) ([]eseis.AccountPlaceEntry, error)
+	DownloadDocument(ctx context.Context, uuid string, w io.Writer) (int64, error)
+	DocumentURL(uuid string) string
+	DownloadAttachment(ctx context.Context, url string, w io.Writer) (int64, error)
+	PrintHTMLFile(ctx context.Context, htmlPath string, outPath string, stamp eseis.PrintStamp) error
+}
+
+// Section exports one kind of Eseis items of a contract
+type Section interface {
+	// Name identifies the section, it is also the name of its dir in the contract output dir
+	Name() string
+	// Export exports the items of the contract of h to contractDir, through h so that the downloads run on the
+	// download pool and the failures are recorded in keep going mode
+	Export(ctx context.Context, h *SectionHandle, contractDir string) error
+}
+
+// SectionHandle gives a section access to the exporter while it exports the items of a contract
+type SectionHandle struct {
+	exporter *Exporter
+	contract eseis.Contract
+	section  string
+}
+
+// API returns the Eseis API used by the exporter
+func (h *SectionHandle) API() EseisAPI {
+	return h.exporter.api
+}
+
+// Contract returns the contract being exported
+func (h *SectionHandle) Contract() eseis.Contract {
+	return h.contract
+}
+
+// SubmitDocument queues the download of a document to folderPath, see ItemFailed for the returned error
+func (h *SectionHandle) SubmitDocument(document eseis.DocumentRef, folderPath string) error {
+	return h.exporter.submitDocument(h.contract, h.section, document, folderPath)
+}
+
+// SubmitAttachments queues the downloads of attachments to folderPath, see ItemFailed for the returned error
+func (h *SectionHandle) SubmitAttachments(attachments []eseis.Attachment, folderPath string) error {
+	return h.exporter.submitAttachments(h.contract, h.section, attachments, folderPath)
+}
+
+// ItemFailed records the failure of an item in keep going mode and returns nil, the section then continues with the
+// next item. Otherwise it returns err and the section must stop.
+func (h *SectionHandle) ItemFailed(ctx context.Context, itemID string, url string, err error) error {
+	return h.exporter.itemFailed(ctx, h.contract, h.section, itemID, url, err)
+}
+
+// RenderReport writes the html page of a report to reportDir and queues its pdf print.
+// It does nothing when rendering is off.
+func (h *SectionHandle) RenderReport(summary eseis.ReportSummary, report eseis.Report, reportDir string) error {
+	if h.exporter.renderer == nil {
+		return nil
+	}
+	return h.exporter.renderReport(h.contract, h.section, summary, report, reportDir)
+}
+
+// RenderForumTopic writes the html page of a forum topic and its posts to forumTopicDir and queues its pdf print.
+// It does nothing when rendering is off.
+func (h *SectionHandle) RenderForumTopic(topic eseis.ForumTopic, posts []eseis.TopicPost, forumTopicDir string) error {
+	if h.exporter.renderer == nil {
+		return nil
+	}
+	return h.exporter.renderForumTopic(h.contract, h.section, topic, posts, forumTopicDir)
+}
+
+// ExportInfoFile writes content as an indented json metadata file
+func (h *SectionHandle) ExportInfoFile(content any, infoFilePath string) error {
+	return h.exporter.ExportInfoFile(content, infoFilePath)
+}
+
+// DefaultSections returns all the sections supported by the exporter
+func DefaultSections() []Section {
+	return []Section{
+		IndividualDocumentsSection{},
+		CoownershipDocumentsSection{},
+		MaintenanceContractsSection{},
+		ReportsSection{},
+		ForumTopicsSection{},
+		BudgetsSection{},
+	}
+}
+
+// Exporter exports the Eseis items of all the contracts of the user to an output dir
+type Exporter struct {
+	api       EseisAPI
+	outDir    string
+	sections  []Section
+	keepGoing bool
+	workers   int
+	manifest  *Manifest
+	pool      *downloadPool
+
+	renderHTML    bool
+	renderPDF     bool
+	renderer      *render.Renderer
+	pendingPrints []pendingPrint
+
+	failuresMu       sync.Mutex
+	failures         []ExportFailure
+	startedAt        time.Time
+	finishedAt       time.Time
+	rateLimiterStats []eseis.RateLimiterStats
+}
+
+// ExporterOption customizes an Exporter built by NewExporter
+type ExporterOption func(*Exporter)
+
+// WithSections sets the sections to export instead of DefaultSections
+func WithSections(sections ...Section) ExporterOption {
+	return func(x *Exporter) {
+		x.sections = sections
+	}
+}
+
+// NewExporter creates an Exporter writing to outDir
+func NewExporter(api EseisAPI, outDir string, opts ...ExporterOption) *Exporter {
+	exporter := &Exporter{api: api, outDir: outDir, sections: DefaultSections(), workers: 1}
+	for _, opt := range opts {
+		opt(exporter)
+	}
+	return exporter
+}
+
+// API returns the Eseis API used by the exporter
+func (x *Exporter) API() EseisAPI {
+	return x.api
+}
+
+// Manifest returns the manifest of the output dir, only available during Export
+func (x *Exporter) Manifest() *Manifest {
+	return x.manifest
+}
+
+// Export exports all the sections of all the contracts, stopping at the first error unless in keep going mode
+func (x *Exporter) Export(ctx context.Context) (err error) {
+	x.startedAt = time.Now()
+	x.failures = nil
+	rateLimiterStats := x.apiRateLimiterStats()
+	defer func() {
+		x.finishedAt = time.Now()
+		x.rateLimiterStats = rateLimiterStatsSince(rateLimiterStats, x.apiRateLimiterStats())
+		logRateLimiterStats(x.rateLimiterStats)
+	}()
+
+	if err = utils.MkDir(x.outDir); err != nil {
+		return err
+	}
+
+	x.renderer = nil
+	x.pendingPrints = nil
+	if x.renderHTML || x.renderPDF {
+		if x.renderer, err = render.NewRenderer(); err != nil {
+			return err
+		}
+	}
+
+	x.manifest, err = OpenManifest(x.outDir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := x.manifest.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		x.manifest = nil
+	}()
+
+	// sections enumerate the items sequentially while the downloads run on the pool workers
+	x.pool = newDownloadPool(ctx, x.workers)
+	err = x.exportContracts(x.pool.ctx)
+	if poolErr := x.pool.wait(); poolErr != nil && (err == nil || errors.Is(err, context.Canceled)) {
+		err = poolErr
+	}
+	x.pool = nil
+	if err == nil {
+		err = x.printRendered(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	if failures := x.Failures(); len(failures) > 0 {
+		return fmt.Errorf("%w: %d items failed", ErrIncompleteExport, len(failures))
+	}
+	return nil
+}
+
+func (x *Exporter) exportContracts(ctx context.Context) error {
+	contracts, err := x.api.GetContracts(ctx, sergicOffer)
+	if err != nil {
+		return fmt.Errorf("failed to get contracts for sergicOffer %s: %w", sergicOffer, err)
+	}
+
+	for _, contract := range contracts {
+		contractOutDir := utils.JoinFilePath(x.outDir, utils.SanitizePath(contract.DisplayName))
+		logrus.Infof("processing contract %d - %s", contract.ID, contract.DisplayName)
+		for _, section := range x.sections {
+			handle := &SectionHandle{exporter: x, contract: contract, section: section.Name()}
+			if err = section.Export(ctx, handle, contractOutDir); err != nil {
+				err = fmt.Errorf("failed to export section %s of contract %d: %w", section.Name(), contract.ID, err)
+				if err = x.itemFailed(ctx, contract, section.Name(), "", "", err); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// submitDownload queues a download on the worker pool, a failure of the download is handled by itemFailed.
+// It returns an error if the export must stop.
+func (x *Exporter) submitDownload(contract eseis.Contract, section string, itemID string, url string, description string, download func(ctx context.Context) error) error {
+	return x.pool.submit(description, func(ctx context.Context) error {
+		if err := download(ctx); err != nil {
+			return x.itemFailed(ctx, contract, section, itemID, url, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,385 @@
+package scrapper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/eseis"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAPI serves the documents of contract folders from memory, the other endpoints return no items
+type fakeAPI struct {
+	contracts []eseis.Contract
+	// folders are the folders of each contract id
+	folders map[int][]eseis.ContractFolder
+	// documents are the documents of each folder id
+	documents map[int][]eseis.ContractDocument
+	// contents are the contents of each document uuid, a missing uuid fails the download
+	contents map[string]string
+	// reports and forumTopics are the reports and forum topics of every place, their screenshots fail as without chrome
+	reports     []eseis.ReportSummary
+	forumTopics []eseis.ForumTopic
+
+	mu          sync.Mutex
+	downloads   map[string]int
+	screenshots int
+}
+
+func pagerOf[T any](items []T) *eseis.Pager[T] {
+	return eseis.NewPager(func(ctx context.Context, page int, perPage int) ([]T, error) {
+		if page > 1 {
+			return nil, nil
+		}
+		return items, nil
+	})
+}
+
+func (f *fakeAPI) GetContracts(ctx context.Context, sergicOffer string) ([]eseis.Contract, error) {
+	return f.contracts, nil
+}
+
+func (f *fakeAPI) IterContractFolders(contractID int, opts ...eseis.PagerOption) *eseis.Pager[eseis.ContractFolder] {
+	return pagerOf(f.folders[contractID])
+}
+
+func (f *fakeAPI) IterContractDocuments(contractID int, folderID int, opts ...eseis.PagerOption) *eseis.Pager[eseis.ContractDocument] {
+	return pagerOf(f.documents[folderID])
+}
+
+func (f *fakeAPI) IterCoownershipFolders(placeID int, opts ...eseis.PagerOption) *eseis.Pager[eseis.CoownershipFolder] {
+	return pagerOf[eseis.CoownershipFolder](nil)
+}
+
+func (f *fakeAPI) IterCoownershipDocuments(placeID int, folderID int, opts ...eseis.PagerOption) *eseis.Pager[eseis.CoownershipDocument] {
+	return pagerOf[eseis.CoownershipDocument](nil)
+}
+
+func (f *fakeAPI) GetMaintenanceContractCategories(ctx context.Context, placeID int) ([]eseis.MaintenanceContractCategory, error) {
+	return nil, nil
+}
+
+func (f *fakeAPI) GetMaintenanceContractDetails(ctx context.Context, maintenanceContractID int) (eseis.MaintenanceContractDetails, error) {
+	return eseis.MaintenanceContractDetails{}, nil
+}
+
+func (f *fakeAPI) IterReportSummaries(placeID int, opts ...eseis.PagerOption) *eseis.Pager[eseis.ReportSummary] {
+	return pagerOf(f.reports)
+}
+
+func (f *fakeAPI) GetReport(ctx context.Context, reportID int) (eseis.Report, error) {
+	return eseis.Report{}, nil
+}
+
+func (f *fakeAPI) CreateReportScreenshot(ctx context.Context, report eseis.ReportSummary, outDir string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.screenshots++
+	return eseis.ErrChromeDisabled
+}
+
+func (f *fakeAPI) IterForumTopics(placeID int, opts ...eseis.PagerOption) *eseis.Pager[eseis.ForumTopic] {
+	return pagerOf(f.forumTopics)
+}
+
+func (f *fakeAPI) AllTopicPosts(ctx context.Context, placeID int, topicID int, opts ...eseis.PagerOption) ([]eseis.TopicPost, error) {
+	return nil, nil
+}
+
+func (f *fakeAPI) CreateForumTopicScreenshot(ctx context.Context, forumTopic eseis.ForumTopic, outDir string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.screenshots++
+	return eseis.ErrChromeDisabled
+}
+
+func (f *fakeAPI) GetFiscalYears(ctx context.Context, placeID int) ([]eseis.FiscalYear, error) {
+	return nil, nil
+}
+
+func (f *fakeAPI) GetBudgets(ctx context.Context, placeID int, fiscalYearID int) ([]eseis.Budget, error) {
+	return nil, nil
+}
+
+func (f *fakeAPI) GetAccountPlaceEntries(ctx context.Context, budgetID int) ([]eseis.AccountPlaceEntry, error) {
+	return nil, nil
+}
+
+func (f *fakeAPI) DownloadDocument(ctx context.Context, uuid string, w io.Writer) (int64, error) {
+	f.mu.Lock()
+	if f.downloads == nil {
+		f.downloads = make(map[string]int)
+	}
+	f.downloads[uuid]++
+	f.mu.Unlock()
+	content, ok := f.contents[uuid]
+	if !ok {
+		return 0, &eseis.APIError{StatusCode: 500, URL: f.DocumentURL(uuid)}
+	}
+	n, err := io.WriteString(w, content)
+	return int64(n), err
+}
+
+func (f *fakeAPI) DocumentURL(uuid string) string {
+	return "http://eseis/v1/sergic_documents?uuid=" + uuid
+}
+
+func (f *fakeAPI) DownloadAttachment(ctx context.Context, url string, w io.Writer) (int64, error) {
+	return 0, errors.New("no attachment")
+}
+
+func (f *fakeAPI) PrintHTMLFile(ctx context.Context, htmlPath string, outPath string, stamp eseis.PrintStamp) error {
+	return eseis.ErrChromeDisabled
+}
+
+func (f *fakeAPI) downloadCount(uuid string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.downloads[uuid]
+}
+
+// newFakeAPI returns an API with a contract holding a folder of documents, one document per uuid
+func newFakeAPI(uuids ...string) *fakeAPI {
+	api := &fakeAPI{
+		contracts: []eseis.Contract{{ID: 1, DisplayName: "contract", PlaceID: 10}},
+		folders:   map[int][]eseis.ContractFolder{1: {{ID: 100, DisplayName: "folder"}}},
+		documents: map[int][]eseis.ContractDocument{},
+		contents:  map[string]string{},
+	}
+	updatedAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, uuid := range uuids {
+		api.documents[100] = append(api.documents[100], eseis.ContractDocument{DocumentRef: eseis.DocumentRef{
+			UUID:        uuid,
+			DisplayName: "document " + uuid,
+			UpdatedAt:   updatedAt,
+		}})
+		api.contents[uuid] = "content of " + uuid
+	}
+	return api
+}
+
+func documentPath(outDir string, uuid string) string {
+	return filepath.Join(outDir, "contract", individualDir, "folder", "document "+uuid+".pdf")
+}
+
+func TestExportDownloadsDocumentsOnce(t *testing.T) {
+	outDir := t.TempDir()
+	api := newFakeAPI("a", "b", "c")
+	exporter := NewExporter(api, outDir, WithWorkers(2))
+
+	for run := 1; run <= 2; run++ {
+		if err := exporter.Export(context.Background()); err != nil {
+			t.Fatalf("export %d failed: %s", run, err)
+		}
+	}
+	for _, uuid := range []string{"a", "b", "c"} {
+		content, err := os.ReadFile(documentPath(outDir, uuid))
+		if err != nil || string(content) != "content of "+uuid {
+			t.Errorf("got document %s %q and error %v", uuid, content, err)
+		}
+		if got := api.downloadCount(uuid); got != 1 {
+			t.Errorf("document %s downloaded %d times, want once", uuid, got)
+		}
+	}
+}
+
+func TestExportWithoutChromeSkipsTheScreenshots(t *testing.T) {
+	outDir := t.TempDir()
+	api := newFakeAPI("a")
+	createdAt := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+	api.reports = []eseis.ReportSummary{{ID: 1, DisplayName: "report", State: reportsOpenedDir, CreatedAt: createdAt}}
+	api.forumTopics = []eseis.ForumTopic{{ID: 2, DisplayName: "topic", CreatedAt: createdAt}}
+	exporter := NewExporter(api, outDir)
+
+	if err := exporter.Export(context.Background()); err != nil {
+		t.Fatalf("got error %v, want the export to skip the screenshots", err)
+	}
+	if api.screenshots != 2 {
+		t.Errorf("got %d screenshots, want the report and the forum topic ones", api.screenshots)
+	}
+	if len(exporter.Failures()) != 0 {
+		t.Errorf("got failures %+v, want none", exporter.Failures())
+	}
+	for _, dir := range []string{
+		filepath.Join(outDir, "contract", reportsDir, reportsOpenedDir, api.reports[0].FileBaseName()),
+		filepath.Join(outDir, "contract", forumTopicsDir, api.forumTopics[0].FileBaseName()),
+	} {
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("got error %v, want the item exported without its screenshot", err)
+		}
+	}
+}
+
+func TestExportStopsAtTheFirstFailure(t *testing.T) {
+	outDir := t.TempDir()
+	api := newFakeAPI("a")
+	delete(api.contents, "a")
+	exporter := NewExporter(api, outDir)
+
+	err := exporter.Export(context.Background())
+	var apiErr *eseis.APIError
+	if !errors.As(err, &apiErr) {
+		t.Errorf("got error %v, want the download error", err)
+	}
+	if _, err = os.Stat(documentPath(outDir, "a")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("failed document should not be written, got %v", err)
+	}
+	if len(exporter.Failures()) != 0 {
+		t.Errorf("got failures %+v, want none outside keep going mode", exporter.Failures())
+	}
+}
+
+func TestExportKeepGoingRecordsFailures(t *testing.T) {
+	outDir := t.TempDir()
+	api := newFakeAPI("a", "b")
+	delete(api.contents, "a")
+	exporter := NewExporter(api, outDir, WithKeepGoing(true), WithWorkers(2))
+
+	err := exporter.Export(context.Background())
+	if !errors.Is(err, ErrIncompleteExport) {
+		t.Fatalf("got error %v, want ErrIncompleteExport", err)
+	}
+	failures := exporter.Failures()
+	if len(failures) != 1 {
+		t.Fatalf("got failures %+v, want 1", failures)
+	}
+	failure := failures[0]
+	if failure.ContractID != 1 || failure.Section != individualDir || failure.ItemID != "a" {
+		t.Errorf("got failure %+v, want document a of the individual section", failure)
+	}
+	if failure.URL != api.DocumentURL("a") {
+		t.Errorf("got failure url %s, want the document endpoint", failure.URL)
+	}
+	if _, err = os.Stat(documentPath(outDir, "a")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("failed document should not be written, got %v", err)
+	}
+	if _, err = os.Stat(documentPath(outDir, "b")); err != nil {
+		t.Errorf("document b should be exported: %s", err)
+	}
+
+	reportPath := filepath.Join(outDir, "failures.json")
+	if err = exporter.WriteFailureReport(reportPath); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("failure report not written: %s", err)
+	}
+	var report FailureReport
+	if err = json.Unmarshal(content, &report); err != nil {
+		t.Fatalf("failed to decode failure report: %s", err)
+	}
+	if report.StartedAt.IsZero() || report.FinishedAt.Before(report.StartedAt) {
+		t.Errorf("got report from %s to %s, want the export run times", report.StartedAt, report.FinishedAt)
+	}
+	if len(report.Failures) != 1 {
+		t.Fatalf("got report failures %+v, want 1", report.Failures)
+	}
+	reported := report.Failures[0]
+	if reported.ContractName != "contract" || reported.ItemID != "a" || reported.URL != failure.URL || reported.Error != failure.Error {
+		t.Errorf("got reported failure %+v, want %+v", reported, failure)
+	}
+	if !strings.Contains(string(content), `"item_id": "a"`) {
+		t.Errorf("got failure report %s, want the snake case keys", content)
+	}
+}
+
+func TestWriteFailureReportWithoutFailures(t *testing.T) {
+	outDir := t.TempDir()
+	exporter := NewExporter(newFakeAPI("a"), outDir, WithKeepGoing(true))
+	if err := exporter.Export(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	reportPath := filepath.Join(outDir, "failures.json")
+	if err := exporter.WriteFailureReport(reportPath); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// an empty list rather than null so that the report can be read without special cases
+	if !strings.Contains(string(content), `"failures": []`) {
+		t.Errorf("got failure report %s, want an empty failure list", content)
+	}
+}
+
+// customSection uses only the exported SectionHandle, as a section defined outside of the package would
+type customSection struct{}
+
+func (customSection) Name() string {
+	return "custom"
+}
+
+func (customSection) Export(ctx context.Context, h *SectionHandle, contractDir string) error {
+	folderPath := filepath.Join(contractDir, "custom")
+	if err := os.MkdirAll(folderPath, 0o755); err != nil {
+		return err
+	}
+	document := eseis.DocumentRef{UUID: "a", DisplayName: "custom document"}
+	if err := h.SubmitDocument(document, folderPath); err != nil {
+		return err
+	}
+	err := fmt.Errorf("failed to export item of contract %d", h.Contract().ID)
+	return h.ItemFailed(ctx, "item", "http://eseis/item", err)
+}
+
+func TestExportCustomSection(t *testing.T) {
+	outDir := t.TempDir()
+	api := newFakeAPI("a")
+	exporter := NewExporter(api, outDir, WithSections(customSection{}), WithKeepGoing(true))
+
+	if err := exporter.Export(context.Background()); !errors.Is(err, ErrIncompleteExport) {
+		t.Fatalf("got error %v, want ErrIncompleteExport", err)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "contract", "custom", "custom document.pdf")); err != nil {
+		t.Errorf("document of the custom section not exported: %s", err)
+	}
+	failures := exporter.Failures()
+	if len(failures) != 1 || failures[0].Section != "custom" || failures[0].ItemID != "item" {
+		t.Errorf("got failures %+v, want the item of the custom section", failures)
+	}
+}
+
+// rateLimitedAPI counts every document download as a request of a documents rate limiter
+type rateLimitedAPI struct {
+	*fakeAPI
+	previousRequests int64
+}
+
+func (f *rateLimitedAPI) RateLimiterStats() []eseis.RateLimiterStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	requests := f.previousRequests
+	for _, count := range f.downloads {
+		requests += int64(count)
+	}
+	return []eseis.RateLimiterStats{{Name: "documents", RequestsPerSecond: 5, Burst: 5, Requests: requests, Waited: time.Duration(requests) * time.Second}}
+}
+
+func TestExportReportsRateLimiterStats(t *testing.T) {
+	// the client sent requests before the export
+	api := &rateLimitedAPI{fakeAPI: newFakeAPI("a", "b", "c"), previousRequests: 5}
+	exporter := NewExporter(api, t.TempDir())
+	if err := exporter.Export(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	stats := exporter.RateLimiterStats()
+	if len(stats) != 1 || stats[0].Name != "documents" {
+		t.Fatalf("got stats %+v, want the documents rate limiter", stats)
+	}
+	if stats[0].Requests != 3 || stats[0].Waited != 3*time.Second {
+		t.Errorf("got %d requests and waited %s, want the 3 downloads of the export", stats[0].Requests, stats[0].Waited)
+	}
+
+	if stats = NewExporter(newFakeAPI(), t.TempDir()).RateLimiterStats(); stats != nil {
+		t.Errorf("got stats %+v for an API without rate limiting, want none", stats)
+	}
+}
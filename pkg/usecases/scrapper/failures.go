@@ -0,0 +1,89 @@
+package scrapper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/eseis"
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/utils"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+// ErrIncompleteExport is returned by Export in keep going mode when some items failed, see Exporter.Failures
+var ErrIncompleteExport = errors.New("export completed with failures")
+
+// ExportFailure describes an item which failed to be exported in keep going mode
+type ExportFailure struct {
+	ContractID   int       `json:"contract_id"`
+	ContractName string    `json:"contract_name"`
+	Section      string    `json:"section"`
+	ItemID       string    `json:"item_id,omitempty"`
+	URL          string    `json:"url,omitempty"`
+	Error        string    `json:"error"`
+	FailedAt     time.Time `json:"failed_at"`
+}
+
+// FailureReport is the machine-readable report of an export run written by WriteFailureReport
+type FailureReport struct {
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"`
+	Failures   []ExportFailure `json:"failures"`
+}
+
+// WithKeepGoing makes the exporter record the items which failed and continue with the next ones instead of
+// stopping at the first error. Export then returns ErrIncompleteExport if any item failed.
+func WithKeepGoing(keepGoing bool) ExporterOption {
+	return func(x *Exporter) {
+		x.keepGoing = keepGoing
+	}
+}
+
+// Failures returns the items which failed during the last export
+func (x *Exporter) Failures() []ExportFailure {
+	x.failuresMu.Lock()
+	defer x.failuresMu.Unlock()
+	return append([]ExportFailure(nil), x.failures...)
+}
+
+// WriteFailureReport writes the failures of the last export as json to path
+func (x *Exporter) WriteFailureReport(path string) error {
+	report := FailureReport{
+		StartedAt:  x.startedAt,
+		FinishedAt: x.finishedAt,
+		Failures:   x.Failures(),
+	}
+	if report.Failures == nil {
+		report.Failures = []ExportFailure{}
+	}
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize failure report: %w", err)
+	}
+	if err = utils.WriteBytesAtomic(path, content, 0660); err != nil {
+		return fmt.Errorf("failed to write failure report %s: %w", path, err)
+	}
+	return nil
+}
+
+// itemFailed records the failure of an item and returns nil in keep going mode, otherwise it returns err.
+// Errors caused by the cancellation of ctx are always returned as the following items would fail the same way.
+func (x *Exporter) itemFailed(ctx context.Context, contract eseis.Contract, section string, itemID string, url string, err error) error {
+	if !x.keepGoing || ctx.Err() != nil {
+		return err
+	}
+	logrus.Errorf("failed to export %s item %s of contract %d, continuing: %s", section, itemID, contract.ID, err)
+	x.failuresMu.Lock()
+	defer x.failuresMu.Unlock()
+	x.failures = append(x.failures, ExportFailure{
+		ContractID:   contract.ID,
+		ContractName: contract.DisplayName,
+		Section:      section,
+		ItemID:       itemID,
+		URL:          url,
+		Error:        err.Error(),
+		FailedAt:     time.Now(),
+	})
+	return nil
+}
@@ -0,0 +1,86 @@
+package scrapper
+
+import (
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/eseis"
+	"time"
+)
+
+// The info files keep the shape they had before the eseis types shared Attachment and DocumentRef, the types below
+// are what is written to them.
+
+type maintenanceContractInfo struct {
+	ID                           int
+	Reference                    string
+	CompanyName                  string
+	UpdatedAt                    time.Time
+	MaintenanceContractDocuments []maintenanceContractDocumentInfo
+}
+
+type maintenanceContractDocumentInfo struct {
+	ID           int
+	UUID         string
+	DisplayName  string
+	DocumentName string
+	UpdatedAt    time.Time
+	FileURL      string
+}
+
+func newMaintenanceContractInfo(details eseis.MaintenanceContractDetails) maintenanceContractInfo {
+	documents := make([]maintenanceContractDocumentInfo, len(details.MaintenanceContractDocuments))
+	for i, document := range details.MaintenanceContractDocuments {
+		documents[i] = maintenanceContractDocumentInfo{
+			ID:           document.ID,
+			UUID:         document.UUID,
+			DisplayName:  document.DisplayName,
+			DocumentName: document.DocumentName,
+			UpdatedAt:    document.UpdatedAt,
+			FileURL:      document.FileURL,
+		}
+	}
+	return maintenanceContractInfo{
+		ID:                           details.ID,
+		Reference:                    details.Reference,
+		CompanyName:                  details.CompanyName,
+		UpdatedAt:                    details.UpdatedAt,
+		MaintenanceContractDocuments: documents,
+	}
+}
+
+type accountPlaceEntryInfo struct {
+	ID                  int
+	UUID                string
+	DisplayName         string
+	Amount              int
+	OperationDate       time.Time
+	UpdatedAt           time.Time
+	Attachment          *accountPlaceEntryAttachmentInfo
+	ProviderID          int
+	ProviderDisplayName string
+	DisplayState        string
+}
+
+type accountPlaceEntryAttachmentInfo struct {
+	DisplayName string
+	FileURL     string
+}
+
+func newAccountPlaceEntryInfo(entry eseis.AccountPlaceEntry) accountPlaceEntryInfo {
+	info := accountPlaceEntryInfo{
+		ID:                  entry.ID,
+		UUID:                entry.UUID,
+		DisplayName:         entry.DisplayName,
+		Amount:              entry.Amount,
+		OperationDate:       entry.OperationDate,
+		UpdatedAt:           entry.UpdatedAt,
+		ProviderID:          entry.ProviderID,
+		ProviderDisplayName: entry.ProviderDisplayName,
+		DisplayState:        entry.DisplayState,
+	}
+	if entry.Attachment != nil {
+		info.Attachment = &accountPlaceEntryAttachmentInfo{
+			DisplayName: entry.Attachment.FileName,
+			FileURL:     entry.Attachment.FileURL,
+		}
+	}
+	return info
+}
@@ -0,0 +1,74 @@
+package scrapper
+
+import (
+	"encoding/json"
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/eseis"
+	"testing"
+	"time"
+)
+
+func TestInfoFilesKeepTheirShape(t *testing.T) {
+	updatedAt := time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		content any
+		want    string
+	}{
+		{
+			"maintenance contract",
+			newMaintenanceContractInfo(eseis.MaintenanceContractDetails{
+				ID:          1,
+				Reference:   "ref",
+				CompanyName: "company",
+				UpdatedAt:   updatedAt,
+				MaintenanceContractDocuments: []eseis.MaintenanceContractDocument{{
+					DocumentRef: eseis.DocumentRef{
+						ID:          2,
+						UUID:        "uuid",
+						DisplayName: "contract",
+						FileURL:     "http://eseis/contract.pdf",
+						UpdatedAt:   updatedAt,
+					},
+					DocumentName: "contract.pdf",
+				}},
+			}),
+			`{"ID":1,"Reference":"ref","CompanyName":"company","UpdatedAt":"2023-01-03T00:00:00Z",` +
+				`"MaintenanceContractDocuments":[{"ID":2,"UUID":"uuid","DisplayName":"contract","DocumentName":"contract.pdf",` +
+				`"UpdatedAt":"2023-01-03T00:00:00Z","FileURL":"http://eseis/contract.pdf"}]}`,
+		},
+		{
+			"account entry",
+			newAccountPlaceEntryInfo(eseis.AccountPlaceEntry{
+				ID:            1,
+				UUID:          "uuid",
+				DisplayName:   "entry",
+				Amount:        100,
+				OperationDate: time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC),
+				UpdatedAt:     updatedAt,
+				Attachment:    &eseis.Attachment{FileName: "invoice.pdf", FileURL: "http://eseis/invoice.pdf"},
+				ProviderID:    2,
+				DisplayState:  "paid",
+			}),
+			`{"ID":1,"UUID":"uuid","DisplayName":"entry","Amount":100,"OperationDate":"2023-01-02T00:00:00Z",` +
+				`"UpdatedAt":"2023-01-03T00:00:00Z","Attachment":{"DisplayName":"invoice.pdf","FileURL":"http://eseis/invoice.pdf"},` +
+				`"ProviderID":2,"ProviderDisplayName":"","DisplayState":"paid"}`,
+		},
+		{
+			"account entry without attachment",
+			newAccountPlaceEntryInfo(eseis.AccountPlaceEntry{ID: 1}),
+			`{"ID":1,"UUID":"","DisplayName":"","Amount":0,"OperationDate":"0001-01-01T00:00:00Z",` +
+				`"UpdatedAt":"0001-01-01T00:00:00Z","Attachment":null,"ProviderID":0,"ProviderDisplayName":"","DisplayState":""}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := json.Marshal(test.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package scrapper
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/utils"
+	"github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ManifestFileName is the name of the manifest file in the export output dir
+const ManifestFileName = ".eseis-manifest.jsonl"
+
+// ManifestEntry describes an item exported to the output dir
+type ManifestEntry struct {
+	Section  string `json:"section"`
+	RemoteID string `json:"remote_id"`
+	// UpdatedAt is the remote update date of the item when it was exported
+	UpdatedAt time.Time `json:"updated_at"`
+	// Path is the path of the exported file, relative to the output dir
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	// ModTime is the modification time of the exported file when it was hashed
+	ModTime    time.Time `json:"mod_time"`
+	ExportedAt time.Time `json:"exported_at"`
+}
+
+func (e ManifestEntry) key() string {
+	return manifestKey(e.Section, e.RemoteID, e.Path)
+}
+
+// manifestKey identifies an item by its exported file as well, the coownership, maintenance, report and forum items
+// of a place are exported once for each contract of the place
+func manifestKey(section string, remoteID string, relativePath string) string {
+	return section + "/" + remoteID + "/" + relativePath
+}
+
+// Manifest records the items exported to an output dir in a JSON lines file, it is safe for concurrent use.
+// Entries are appended as items are exported and the file is compacted on Close, the last entry of an exported file wins.
+type Manifest struct {
+	outDir  string
+	path    string
+	mu      sync.Mutex
+	entries map[string]ManifestEntry
+	file    *os.File
+}
+
+// OpenManifest loads the manifest of the output dir, creating it if needed
+func OpenManifest(outDir string) (*Manifest, error) {
+	path := filepath.Join(outDir, ManifestFileName)
+	entries := make(map[string]ManifestEntry)
+
+	existingFile, err := os.Open(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to open manifest %s: %w", path, err)
+	}
+	if err == nil {
+		defer existingFile.Close()
+		scanner := bufio.NewScanner(existingFile)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			var entry ManifestEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				// a crash may leave a truncated last line, the item will simply be exported again
+				continue
+			}
+			entries[entry.key()] = entry
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read manifest %s: %w", path, err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0660)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest %s for writing: %w", path, err)
+	}
+	return &Manifest{outDir: outDir, path: path, entries: entries, file: file}, nil
+}
+
+// Get returns the entry of an item exported to path
+func (m *Manifest) Get(section string, remoteID string, path string) (ManifestEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.entries[manifestKey(section, remoteID, m.relativePath(path))]
+	return entry, ok
+}
+
+// Entries returns all the entries sorted by path
+func (m *Manifest) Entries() []ManifestEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sortedEntries()
+}
+
+func (m *Manifest) sortedEntries() []ManifestEntry {
+	entries := make([]ManifestEntry, 0, len(m.entries))
+	for _, entry := range m.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	return entries
+}
+
+// IsUpToDate returns true if the item was already exported to path at its remote update date and the exported file
+// still has its recorded size and SHA-256. The file is only hashed when its modification time changed since it was
+// recorded, the new modification time is then recorded if the file did not change.
+func (m *Manifest) IsUpToDate(section string, remoteID string, updatedAt time.Time, path string) bool {
+	entry, ok := m.Get(section, remoteID, path)
+	if !ok || updatedAt.After(entry.UpdatedAt) {
+		return false
+	}
+	fileInfo, err := os.Stat(path)
+	if err != nil || fileInfo.Size() != entry.Size {
+		return false
+	}
+	if fileInfo.ModTime().Equal(entry.ModTime) {
+		return true
+	}
+	if err = verifyFile(path, entry); err != nil {
+		return false
+	}
+	entry.ModTime = fileInfo.ModTime()
+	if err = m.Record(entry); err != nil {
+		logrus.Warnf("failed to record the modification time of %s: %s", path, err)
+	}
+	return true
+}
+
+// AdoptLegacyFile records an existing file without an entry, exported before the manifest existed or after it was
+// lost, if it is more recent than the remote update date of its item. It returns true if the file was adopted, it is
+// then up to date. Older files are left to be exported again.
+func (m *Manifest) AdoptLegacyFile(section string, remoteID string, updatedAt time.Time, path string) (bool, error) {
+	if _, ok := m.Get(section, remoteID, path); ok {
+		return false, nil
+	}
+	fileInfo, err := os.Stat(path)
+	if err != nil || !updatedAt.Before(fileInfo.ModTime()) {
+		return false, nil
+	}
+	if err = m.RecordFile(section, remoteID, updatedAt, path); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RecordFile records the item exported to path, computing its size and checksum from the file
+func (m *Manifest) RecordFile(section string, remoteID string, updatedAt time.Time, path string) error {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	size, checksum, err := hashFile(path)
+	if err != nil {
+		return err
+	}
+	return m.Record(ManifestEntry{
+		Section:    section,
+		RemoteID:   remoteID,
+		UpdatedAt:  updatedAt,
+		Path:       m.relativePath(path),
+		Size:       size,
+		SHA256:     checksum,
+		ModTime:    fileInfo.ModTime(),
+		ExportedAt: time.Now(),
+	})
+}
+
+// Record adds or replaces the entry of an item
+func (m *Manifest) Record(entry ManifestEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest entry: %w", err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err = m.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write manifest %s: %w", m.path, err)
+	}
+	m.entries[entry.key()] = entry
+	return nil
+}
+
+// Verify checks the exported file of the entry still matches its recorded size and checksum
+func (m *Manifest) Verify(entry ManifestEntry) error {
+	return verifyFile(filepath.Join(m.outDir, entry.Path), entry)
+}
+
+func verifyFile(path string, entry ManifestEntry) error {
+	size, checksum, err := hashFile(path)
+	if err != nil {
+		return err
+	}
+	if size != entry.Size || checksum != entry.SHA256 {
+		return fmt.Errorf("file %s does not match the manifest", entry.Path)
+	}
+	return nil
+}
+
+// Close compacts the manifest file to keep only the last entry of each exported file
+func (m *Manifest) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.file.Close(); err != nil {
+		return fmt.Errorf("failed to close manifest %s: %w", m.path, err)
+	}
+	return utils.WriteFileAtomic(m.path, 0660, func(f *os.File) error {
+		encoder := json.NewEncoder(f)
+		for _, entry := range m.sortedEntries() {
+			if err := encoder.Encode(entry); err != nil {
+				return fmt.Errorf("failed to write manifest %s: %w", m.path, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (m *Manifest) relativePath(path string) string {
+	relativePath, err := filepath.Rel(m.outDir, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(relativePath)
+}
+
+func hashFile(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,252 @@
+package scrapper
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func openTestManifest(t *testing.T, outDir string) *Manifest {
+	t.Helper()
+	manifest, err := OpenManifest(outDir)
+	if err != nil {
+		t.Fatalf("failed to open manifest: %s", err)
+	}
+	return manifest
+}
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines++
+	}
+	return lines
+}
+
+func TestManifestReloadsAppendedEntriesLastWins(t *testing.T) {
+	outDir := t.TempDir()
+	path := filepath.Join(outDir, "contract", "document.pdf")
+	firstUpdate := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	secondUpdate := firstUpdate.Add(24 * time.Hour)
+
+	manifest := openTestManifest(t, outDir)
+	writeTestFile(t, path, "first")
+	if err := manifest.RecordFile("documents", "uuid", firstUpdate, path); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, path, "second version")
+	if err := manifest.RecordFile("documents", "uuid", secondUpdate, path); err != nil {
+		t.Fatal(err)
+	}
+	// simulate a crash: the appended lines are reloaded without compaction
+	manifest.file.Close()
+	if got := countLines(t, manifest.path); got != 2 {
+		t.Errorf("got %d appended lines, want 2", got)
+	}
+
+	manifest = openTestManifest(t, outDir)
+	entry, ok := manifest.Get("documents", "uuid", path)
+	if !ok {
+		t.Fatal("entry not reloaded")
+	}
+	if !entry.UpdatedAt.Equal(secondUpdate) || entry.Size != int64(len("second version")) {
+		t.Errorf("got entry %+v, want the last recorded one", entry)
+	}
+	if !manifest.IsUpToDate("documents", "uuid", secondUpdate, path) {
+		t.Error("file should be up to date")
+	}
+	if manifest.IsUpToDate("documents", "uuid", secondUpdate.Add(time.Hour), path) {
+		t.Error("file updated remotely should not be up to date")
+	}
+	if err := manifest.Verify(entry); err != nil {
+		t.Errorf("verify failed: %s", err)
+	}
+	if err := manifest.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := countLines(t, manifest.path); got != 1 {
+		t.Errorf("got %d lines after compaction, want 1", got)
+	}
+}
+
+func TestManifestKeepsSharedPlaceItemsOfEachContract(t *testing.T) {
+	outDir := t.TempDir()
+	updatedAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	// the items of a place are exported once for each of its contracts
+	paths := []string{
+		filepath.Join(outDir, "contract_1", "forum", "attachment.pdf"),
+		filepath.Join(outDir, "contract_2", "forum", "attachment.pdf"),
+	}
+
+	manifest := openTestManifest(t, outDir)
+	for _, path := range paths {
+		writeTestFile(t, path, "attachment")
+		if err := manifest.RecordFile("forum", "42", updatedAt, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := manifest.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest = openTestManifest(t, outDir)
+	defer manifest.Close()
+	if got := len(manifest.Entries()); got != len(paths) {
+		t.Errorf("got %d entries, want %d", got, len(paths))
+	}
+	for _, path := range paths {
+		if !manifest.IsUpToDate("forum", "42", updatedAt, path) {
+			t.Errorf("%s should be up to date", path)
+		}
+	}
+}
+
+func TestManifestAdoptsLegacyFiles(t *testing.T) {
+	updatedAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		modTime     time.Time
+		wantAdopted bool
+	}{
+		{"exported after the remote update", updatedAt.Add(time.Hour), true},
+		{"exported before the remote update", updatedAt.Add(-time.Hour), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			outDir := t.TempDir()
+			path := filepath.Join(outDir, "contract", "document.pdf")
+			writeTestFile(t, path, "legacy")
+			if err := os.Chtimes(path, test.modTime, test.modTime); err != nil {
+				t.Fatal(err)
+			}
+			manifest := openTestManifest(t, outDir)
+			defer manifest.Close()
+
+			adopted, err := manifest.AdoptLegacyFile("documents", "uuid", updatedAt, path)
+			if err != nil || adopted != test.wantAdopted {
+				t.Fatalf("got adopted %t and error %v, want adopted %t", adopted, err, test.wantAdopted)
+			}
+			entry, ok := manifest.Get("documents", "uuid", path)
+			if ok != test.wantAdopted {
+				t.Fatalf("got entry %+v, want an entry %t", entry, test.wantAdopted)
+			}
+			if manifest.IsUpToDate("documents", "uuid", updatedAt, path) != test.wantAdopted {
+				t.Errorf("got up to date %t, want %t", !test.wantAdopted, test.wantAdopted)
+			}
+			if !test.wantAdopted {
+				return
+			}
+			if !entry.UpdatedAt.Equal(updatedAt) || entry.Size != int64(len("legacy")) || entry.SHA256 == "" {
+				t.Errorf("got entry %+v, want the remote update date, size and checksum of the file", entry)
+			}
+			if adopted, _ = manifest.AdoptLegacyFile("documents", "uuid", updatedAt, path); adopted {
+				t.Error("file with an entry should not be adopted again")
+			}
+		})
+	}
+
+	outDir := t.TempDir()
+	manifest := openTestManifest(t, outDir)
+	defer manifest.Close()
+	if adopted, _ := manifest.AdoptLegacyFile("documents", "missing", updatedAt, filepath.Join(outDir, "missing.pdf")); adopted {
+		t.Error("missing file should not be adopted")
+	}
+}
+
+func TestManifestIsUpToDateChecksTheFile(t *testing.T) {
+	updatedAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		content string
+		remove  bool
+		want    bool
+	}{
+		{"rewritten unchanged", "exported", false, true},
+		{"same size but corrupted", "exp0rted", false, false},
+		{"truncated", "export", false, false},
+		{"removed", "", true, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			outDir := t.TempDir()
+			path := filepath.Join(outDir, "contract", "document.pdf")
+			writeTestFile(t, path, "exported")
+			manifest := openTestManifest(t, outDir)
+			defer manifest.Close()
+			if err := manifest.RecordFile("documents", "uuid", updatedAt, path); err != nil {
+				t.Fatal(err)
+			}
+
+			if test.remove {
+				if err := os.Remove(path); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				writeTestFile(t, path, test.content)
+				// make sure the rewrite changes the modification time, the file is then hashed
+				modTime := time.Now().Add(time.Minute)
+				if err := os.Chtimes(path, modTime, modTime); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := manifest.IsUpToDate("documents", "uuid", updatedAt, path); got != test.want {
+				t.Errorf("got up to date %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestManifestIsUpToDateHashesOnlyModifiedFiles(t *testing.T) {
+	outDir := t.TempDir()
+	path := filepath.Join(outDir, "contract", "document.pdf")
+	updatedAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeTestFile(t, path, "exported")
+	manifest := openTestManifest(t, outDir)
+	defer manifest.Close()
+	if err := manifest.RecordFile("documents", "uuid", updatedAt, path); err != nil {
+		t.Fatal(err)
+	}
+	entry, _ := manifest.Get("documents", "uuid", path)
+
+	// a change keeping the size and the modification time is not detected, the file is not hashed
+	writeTestFile(t, path, "exp0rted")
+	if err := os.Chtimes(path, entry.ModTime, entry.ModTime); err != nil {
+		t.Fatal(err)
+	}
+	if !manifest.IsUpToDate("documents", "uuid", updatedAt, path) {
+		t.Error("file with the recorded size and modification time should be up to date without hashing")
+	}
+
+	// a file touched without changes is hashed once, then its new modification time is recorded
+	writeTestFile(t, path, "exported")
+	modTime := entry.ModTime.Add(time.Minute)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	if !manifest.IsUpToDate("documents", "uuid", updatedAt, path) {
+		t.Error("touched file should be up to date")
+	}
+	if entry, _ = manifest.Get("documents", "uuid", path); !entry.ModTime.Equal(modTime) {
+		t.Errorf("got recorded modification time %s, want %s", entry.ModTime, modTime)
+	}
+}
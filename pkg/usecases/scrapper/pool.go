@@ -0,0 +1,136 @@
+package scrapper
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// WithWorkers sets the number of concurrent downloads, 1 downloads one file at a time
+func WithWorkers(workers int) ExporterOption {
+	return func(x *Exporter) {
+		x.workers = workers
+	}
+}
+
+// downloadPool runs download jobs on a bounded number of workers while the sections keep enumerating items.
+// Job completions are logged in submission order and the first error cancels the remaining jobs.
+type downloadPool struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	jobs    chan *downloadJob
+	ordered chan *downloadJob
+
+	workersWG  sync.WaitGroup
+	loggerDone chan struct{}
+	seq        int
+
+	errMu sync.Mutex
+	err   error
+}
+
+type downloadJob struct {
+	seq         int
+	description string
+	run         func(ctx context.Context) error
+	duration    time.Duration
+	err         error
+	done        chan struct{}
+}
+
+func newDownloadPool(ctx context.Context, workers int) *downloadPool {
+	if workers < 1 {
+		workers = 1
+	}
+	poolCtx, cancel := context.WithCancel(ctx)
+	pool := &downloadPool{
+		ctx:        poolCtx,
+		cancel:     cancel,
+		jobs:       make(chan *downloadJob),
+		ordered:    make(chan *downloadJob, 4*workers),
+		loggerDone: make(chan struct{}),
+	}
+	for i := 0; i < workers; i++ {
+		pool.workersWG.Add(1)
+		go pool.work()
+	}
+	go pool.logCompletions()
+	return pool
+}
+
+// submit queues a job, blocking while all the workers are busy. It returns an error if the pool was stopped.
+func (p *downloadPool) submit(description string, run func(ctx context.Context) error) error {
+	if err := p.stopError(); err != nil {
+		return err
+	}
+	p.seq++
+	job := &downloadJob{seq: p.seq, description: description, run: run, done: make(chan struct{})}
+	select {
+	case p.ordered <- job:
+	case <-p.ctx.Done():
+		return p.stopError()
+	}
+	select {
+	case p.jobs <- job:
+		return nil
+	case <-p.ctx.Done():
+		job.err = p.stopError()
+		close(job.done)
+		return job.err
+	}
+}
+
+func (p *downloadPool) work() {
+	defer p.workersWG.Done()
+	for job := range p.jobs {
+		start := time.Now()
+		job.err = job.run(p.ctx)
+		job.duration = time.Since(start)
+		if job.err != nil {
+			p.fail(job.err)
+		}
+		close(job.done)
+	}
+}
+
+func (p *downloadPool) logCompletions() {
+	defer close(p.loggerDone)
+	for job := range p.ordered {
+		<-job.done
+		if job.err == nil {
+			logrus.Infof("[%d] %s done in %s", job.seq, job.description, job.duration.Round(time.Millisecond))
+		}
+	}
+}
+
+// fail stops the pool, keeping the first error
+func (p *downloadPool) fail(err error) {
+	p.errMu.Lock()
+	defer p.errMu.Unlock()
+	if p.err == nil {
+		p.err = err
+		p.cancel()
+	}
+}
+
+// stopError returns the error which stopped the pool, if any
+func (p *downloadPool) stopError() error {
+	p.errMu.Lock()
+	defer p.errMu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	return p.ctx.Err()
+}
+
+// wait waits for the queued jobs to complete, shuts the workers down and returns the first error
+func (p *downloadPool) wait() error {
+	close(p.jobs)
+	p.workersWG.Wait()
+	close(p.ordered)
+	<-p.loggerDone
+	err := p.stopError()
+	p.cancel()
+	return err
+}
@@ -0,0 +1,172 @@
+package scrapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus/hooks/test"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDownloadPoolLogsCompletionsInSubmissionOrder(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	pool := newDownloadPool(context.Background(), 4)
+	const jobs = 8
+	for i := 0; i < jobs; i++ {
+		// the first jobs complete last
+		delay := time.Duration(jobs-i) * 5 * time.Millisecond
+		if err := pool.submit(fmt.Sprintf("job %d", i+1), func(ctx context.Context) error {
+			time.Sleep(delay)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pool.wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	var completions []string
+	for _, entry := range hook.AllEntries() {
+		if strings.Contains(entry.Message, " done in ") {
+			completions = append(completions, entry.Message)
+		}
+	}
+	if len(completions) != jobs {
+		t.Fatalf("got completions %v, want %d", completions, jobs)
+	}
+	for i, completion := range completions {
+		if want := fmt.Sprintf("[%d] job %d done in ", i+1, i+1); !strings.HasPrefix(completion, want) {
+			t.Errorf("got completion %q at position %d, want %q", completion, i+1, want)
+		}
+	}
+}
+
+func TestDownloadPoolBoundsConcurrency(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers int
+		want    int32
+	}{
+		{"single worker", 1, 1},
+		{"several workers", 3, 3},
+		{"invalid workers", 0, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var running, maxRunning int32
+			pool := newDownloadPool(context.Background(), test.workers)
+			for i := 0; i < 10; i++ {
+				err := pool.submit("job", func(ctx context.Context) error {
+					current := atomic.AddInt32(&running, 1)
+					for {
+						highest := atomic.LoadInt32(&maxRunning)
+						if current <= highest || atomic.CompareAndSwapInt32(&maxRunning, highest, current) {
+							break
+						}
+					}
+					time.Sleep(10 * time.Millisecond)
+					atomic.AddInt32(&running, -1)
+					return nil
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := pool.wait(); err != nil {
+				t.Fatal(err)
+			}
+			if maxRunning != test.want {
+				t.Errorf("got %d concurrent jobs, want %d", maxRunning, test.want)
+			}
+		})
+	}
+}
+
+func TestDownloadPoolStopsAtTheFirstError(t *testing.T) {
+	pool := newDownloadPool(context.Background(), 2)
+	jobErr := errors.New("download failed")
+	cancelled := make(chan struct{})
+	// a long job which must be cancelled by the failure of the other one
+	if err := pool.submit("slow job", func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			close(cancelled)
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return nil
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.submit("failing job", func(ctx context.Context) error {
+		return jobErr
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("running job not cancelled after the failure")
+	}
+	if err := pool.submit("next job", func(ctx context.Context) error {
+		t.Error("job submitted after the failure was run")
+		return nil
+	}); !errors.Is(err, jobErr) {
+		t.Errorf("got submit error %v, want the job error", err)
+	}
+	if err := pool.wait(); !errors.Is(err, jobErr) {
+		t.Errorf("got wait error %v, want the first job error", err)
+	}
+}
+
+func TestDownloadPoolShutsDownWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pool := newDownloadPool(ctx, 1)
+	started := make(chan struct{})
+	if err := pool.submit("running job", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	// the only worker is busy, the submit blocks until the cancellation
+	submitted := make(chan error, 1)
+	go func() {
+		submitted <- pool.submit("queued job", func(ctx context.Context) error {
+			return nil
+		})
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-submitted:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got submit error %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("submit still blocked after the cancellation")
+	}
+	waited := make(chan error, 1)
+	go func() {
+		waited <- pool.wait()
+	}()
+	select {
+	case err := <-waited:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got wait error %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pool workers not shut down after the cancellation")
+	}
+}
@@ -0,0 +1,50 @@
+package scrapper
+
+import (
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/eseis"
+	"github.com/sirupsen/logrus"
+)
+
+// RateLimitedAPI is implemented by the APIs with client side rate limiting, such as the Eseis client.
+// The exporter then reports the rate limiter statistics of each export, see Exporter.RateLimiterStats.
+type RateLimitedAPI interface {
+	RateLimiterStats() []eseis.RateLimiterStats
+}
+
+// RateLimiterStats returns the statistics of the API rate limiters for the requests sent during the last export,
+// nil if the API does not implement RateLimitedAPI
+func (x *Exporter) RateLimiterStats() []eseis.RateLimiterStats {
+	return x.rateLimiterStats
+}
+
+func (x *Exporter) apiRateLimiterStats() []eseis.RateLimiterStats {
+	api, ok := x.api.(RateLimitedAPI)
+	if !ok {
+		return nil
+	}
+	return api.RateLimiterStats()
+}
+
+// rateLimiterStatsSince removes the requests and waits counted in before from the stats, the API client may have
+// sent requests before the export
+func rateLimiterStatsSince(before []eseis.RateLimiterStats, stats []eseis.RateLimiterStats) []eseis.RateLimiterStats {
+	stats = append([]eseis.RateLimiterStats(nil), stats...)
+	for i := range stats {
+		for _, previous := range before {
+			if previous.Name == stats[i].Name {
+				stats[i].Requests -= previous.Requests
+				stats[i].Waited -= previous.Waited
+			}
+		}
+	}
+	return stats
+}
+
+func logRateLimiterStats(stats []eseis.RateLimiterStats) {
+	for _, bucket := range stats {
+		logrus.Infof(
+			"rate limiter %s: %d requests, waited %s, %.1f/%d available at %.2f req/s",
+			bucket.Name, bucket.Requests, bucket.Waited, bucket.Available, bucket.Burst, bucket.RequestsPerSecond,
+		)
+	}
+}
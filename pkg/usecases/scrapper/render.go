@@ -0,0 +1,109 @@
+package scrapper
+
+import (
+	"context"
+	"fmt"
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/eseis"
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/render"
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/utils"
+	"github.com/sirupsen/logrus"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	renderedHTMLSuffix = "_rendered.html"
+	renderedPDFSuffix  = "_rendered.pdf"
+)
+
+// WithRenderedHTML makes the exporter render a standalone html page of each report and forum topic from the API
+// data, next to its attachments. It does not need a browser.
+func WithRenderedHTML(renderHTML bool) ExporterOption {
+	return func(x *Exporter) {
+		x.renderHTML = renderHTML
+	}
+}
+
+// WithRenderedPDF makes the exporter print the rendered html pages to pdf, it implies WithRenderedHTML.
+// Unlike the html pages, the pdf prints need a browser: they are printed by chrome once all the attachments are
+// downloaded, without logging in to the web UI. Each print fails with eseis.ErrChromeDisabled if chrome is disabled.
+func WithRenderedPDF(renderPDF bool) ExporterOption {
+	return func(x *Exporter) {
+		x.renderPDF = renderPDF
+	}
+}
+
+// pendingPrint is a rendered html page waiting to be printed to pdf
+type pendingPrint struct {
+	contract eseis.Contract
+	section  string
+	htmlPath string
+	pdfPath  string
+	stamp    eseis.PrintStamp
+}
+
+// renderReport writes the html page of a report to reportDir and queues its pdf print
+func (x *Exporter) renderReport(contract eseis.Contract, section string, summary eseis.ReportSummary, report eseis.Report, reportDir string) error {
+	basePath := utils.JoinFilePath(reportDir, summary.FileBaseName())
+	page := render.ReportPage{Summary: summary, Report: report, RenderedAt: time.Now()}
+	err := x.writeRendered(basePath+renderedHTMLSuffix, func(f *os.File) error {
+		return x.renderer.RenderReport(f, page)
+	})
+	if err != nil {
+		return err
+	}
+	x.queuePrint(contract, section, basePath, eseis.PrintStamp{URL: summary.URL, ItemID: strconv.Itoa(summary.ID)})
+	return nil
+}
+
+// renderForumTopic writes the html page of a forum topic to forumTopicDir and queues its pdf print
+func (x *Exporter) renderForumTopic(contract eseis.Contract, section string, topic eseis.ForumTopic, posts []eseis.TopicPost, forumTopicDir string) error {
+	basePath := utils.JoinFilePath(forumTopicDir, topic.FileBaseName())
+	page := render.ForumTopicPage{Topic: topic, Posts: posts, RenderedAt: time.Now()}
+	err := x.writeRendered(basePath+renderedHTMLSuffix, func(f *os.File) error {
+		return x.renderer.RenderForumTopic(f, page)
+	})
+	if err != nil {
+		return err
+	}
+	x.queuePrint(contract, section, basePath, eseis.PrintStamp{URL: topic.URL, ItemID: strconv.Itoa(topic.ID)})
+	return nil
+}
+
+func (x *Exporter) writeRendered(htmlPath string, write func(f *os.File) error) error {
+	if err := utils.WriteFileAtomic(htmlPath, 0660, write); err != nil {
+		return fmt.Errorf("failed to write rendered page %s: %w", htmlPath, err)
+	}
+	return nil
+}
+
+// queuePrint queues the pdf print of a rendered page, the sections run sequentially so no lock is needed
+func (x *Exporter) queuePrint(contract eseis.Contract, section string, basePath string, stamp eseis.PrintStamp) {
+	if !x.renderPDF {
+		return
+	}
+	x.pendingPrints = append(x.pendingPrints, pendingPrint{
+		contract: contract,
+		section:  section,
+		htmlPath: basePath + renderedHTMLSuffix,
+		pdfPath:  basePath + renderedPDFSuffix,
+		stamp:    stamp,
+	})
+}
+
+// printRendered prints the queued pages to pdf, it must run after the downloads so that the images are included
+func (x *Exporter) printRendered(ctx context.Context) error {
+	prints := x.pendingPrints
+	x.pendingPrints = nil
+	for _, p := range prints {
+		logrus.Debugf("printing %s to %s", p.htmlPath, p.pdfPath)
+		if err := x.api.PrintHTMLFile(ctx, p.htmlPath, p.pdfPath, p.stamp); err != nil {
+			err = fmt.Errorf("failed to print rendered page %s: %w", p.htmlPath, err)
+			if err = x.itemFailed(ctx, p.contract, p.section, p.stamp.ItemID, p.stamp.URL, err); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,348 @@
+package scrapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/eseis"
+	"github.com/idkw/eseisscrapper/pkg/infrastructure/utils"
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	individualDir          = "individual"
+	coownershipDir         = "coownership"
+	maintenanceDir         = "maintenance"
+	reportsDir             = "reports"
+	reportsOpenedDir       = "opened"
+	reportsAcknowledgedDir = "acknowledged"
+	reportsResolvedDir     = "resolved"
+	forumTopicsDir         = "forum"
+	budgetsDir             = "budgets"
+)
+
+// IndividualDocumentsSection exports the documents of the contract folders
+type IndividualDocumentsSection struct{}
+
+func (IndividualDocumentsSection) Name() string {
+	return individualDir
+}
+
+func (IndividualDocumentsSection) Export(ctx context.Context, h *SectionHandle, outDir string) error {
+	contract := h.Contract()
+	folders := h.API().IterContractFolders(contract.ID)
+	for folders.Next(ctx) {
+		folder := folders.Item()
+		logrus.Infof("----------\nFolder %d:%s", folder.ID, folder.DisplayName)
+
+		folderPath := utils.JoinFilePath(outDir, individualDir, utils.SanitizePath(folder.DisplayName))
+		if err := utils.MkDir(folderPath); err != nil {
+			return err
+		}
+
+		documents := h.API().IterContractDocuments(contract.ID, folder.ID)
+		for documents.Next(ctx) {
+			document := documents.Item()
+			if err := h.SubmitDocument(document.DocumentRef, folderPath); err != nil {
+				return err
+			}
+		}
+		if err := documents.Err(); err != nil {
+			return fmt.Errorf("failed to get contract documents for id=%d folder=%d: %w", contract.ID, folder.ID, err)
+		}
+	}
+	if err := folders.Err(); err != nil {
+		return fmt.Errorf("failed to get contract folders for id=%d: %w", contract.ID, err)
+	}
+	return nil
+}
+
+// CoownershipDocumentsSection exports the documents of the coownership folders
+type CoownershipDocumentsSection struct{}
+
+func (CoownershipDocumentsSection) Name() string {
+	return coownershipDir
+}
+
+func (CoownershipDocumentsSection) Export(ctx context.Context, h *SectionHandle, outDir string) error {
+	contract := h.Contract()
+	coownershipFolders := h.API().IterCoownershipFolders(contract.PlaceID)
+	for coownershipFolders.Next(ctx) {
+		coownershipFolder := coownershipFolders.Item()
+		logrus.Infof("----------\nCoownershipFolder %d:%s", coownershipFolder.ID, coownershipFolder.DisplayName)
+
+		folderPath := utils.JoinFilePath(outDir, coownershipDir, utils.SanitizePath(coownershipFolder.DisplayName))
+		if err := utils.MkDir(folderPath); err != nil {
+			return err
+		}
+
+		documents := h.API().IterCoownershipDocuments(contract.PlaceID, coownershipFolder.ID)
+		for documents.Next(ctx) {
+			document := documents.Item()
+			if err := h.SubmitDocument(document.DocumentRef, folderPath); err != nil {
+				return err
+			}
+		}
+		if err := documents.Err(); err != nil {
+			return fmt.Errorf("failed to get coownership documents for placeId=%d coownershipFolder=%d: %w", contract.PlaceID, coownershipFolder.ID, err)
+		}
+	}
+	if err := coownershipFolders.Err(); err != nil {
+		return fmt.Errorf("failed to get coownership folders for placeId=%d: %w", contract.PlaceID, err)
+	}
+	return nil
+}
+
+// MaintenanceContractsSection exports the documents and details of the maintenance contracts
+type MaintenanceContractsSection struct{}
+
+func (MaintenanceContractsSection) Name() string {
+	return maintenanceDir
+}
+
+func (MaintenanceContractsSection) Export(ctx context.Context, h *SectionHandle, outDir string) error {
+	contract := h.Contract()
+	categories, err := h.API().GetMaintenanceContractCategories(ctx, contract.PlaceID)
+	if err != nil {
+		return fmt.Errorf("failed to get maintenance contract categories for placeID %d: %w", contract.PlaceID, err)
+	}
+	for _, category := range categories {
+		categoryFolderPath := utils.JoinFilePath(outDir, maintenanceDir, utils.SanitizePath(category.DisplayName))
+		for _, maintenanceContract := range category.MaintenanceContracts {
+			maintenanceContractFolderPath := utils.JoinFilePath(categoryFolderPath, utils.SanitizePath(maintenanceContract.CompanyName+"_"+maintenanceContract.Reference))
+			if err = utils.MkDir(maintenanceContractFolderPath); err != nil {
+				return err
+			}
+
+			maintenanceContractDetails, err := h.API().GetMaintenanceContractDetails(ctx, maintenanceContract.ID)
+			if err != nil {
+				err = fmt.Errorf("failed to get maintenance contract details for id %d: %w", maintenanceContract.ID, err)
+				if err = h.ItemFailed(ctx, strconv.Itoa(maintenanceContract.ID), "", err); err != nil {
+					return err
+				}
+				continue
+			}
+			for _, document := range maintenanceContractDetails.MaintenanceContractDocuments {
+				if err := h.SubmitDocument(document.DocumentRef, maintenanceContractFolderPath); err != nil {
+					return err
+				}
+			}
+
+			// add additional info file for metadata
+			if err = h.ExportInfoFile(newMaintenanceContractInfo(maintenanceContractDetails), utils.JoinFilePath(maintenanceContractFolderPath, "info.json")); err != nil {
+				if err = h.ItemFailed(ctx, strconv.Itoa(maintenanceContract.ID), "", err); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ReportsSection exports a screenshot, the attachments and optionally a rendered page of the reports
+type ReportsSection struct{}
+
+func (ReportsSection) Name() string {
+	return reportsDir
+}
+
+func (ReportsSection) Export(ctx context.Context, h *SectionHandle, outDir string) error {
+	contract := h.Contract()
+	for _, stateDir := range []string{reportsOpenedDir, reportsAcknowledgedDir, reportsResolvedDir} {
+		if err := utils.MkDir(utils.JoinFilePath(outDir, reportsDir, stateDir)); err != nil {
+			return err
+		}
+	}
+
+	reportSummaries := h.API().IterReportSummaries(contract.PlaceID)
+	for reportSummaries.Next(ctx) {
+		reportSummary := reportSummaries.Item()
+		logrus.Infof("----------\nReport %d:%s", reportSummary.ID, reportSummary.DisplayName)
+
+		reportDir := utils.JoinFilePath(outDir, reportsDir, reportSummary.State, reportSummary.FileBaseName())
+		if err := utils.MkDir(reportDir); err != nil {
+			return err
+		}
+
+		if err := h.API().CreateReportScreenshot(ctx, reportSummary, reportDir); err != nil && !errors.Is(err, eseis.ErrChromeDisabled) {
+			err = fmt.Errorf("failed screenshot for report %d: %w", reportSummary.ID, err)
+			if err = h.ItemFailed(ctx, strconv.Itoa(reportSummary.ID), reportSummary.URL, err); err != nil {
+				return err
+			}
+		}
+
+		report, err := h.API().GetReport(ctx, reportSummary.ID)
+		if err != nil {
+			err = fmt.Errorf("failed to get report %d: %w", reportSummary.ID, err)
+			if err = h.ItemFailed(ctx, strconv.Itoa(reportSummary.ID), reportSummary.URL, err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := h.SubmitAttachments(report.Attachments, reportDir); err != nil {
+			return err
+		}
+
+		for _, event := range report.ReportEvents {
+			if err := h.SubmitAttachments(event.Attachments, reportDir); err != nil {
+				return err
+			}
+		}
+
+		if err := h.RenderReport(reportSummary, report, reportDir); err != nil {
+			if err = h.ItemFailed(ctx, strconv.Itoa(reportSummary.ID), reportSummary.URL, err); err != nil {
+				return err
+			}
+		}
+	}
+	if err := reportSummaries.Err(); err != nil {
+		return fmt.Errorf("failed to get report summaries for placeId=%d: %w", contract.PlaceID, err)
+	}
+	return nil
+}
+
+// ForumTopicsSection exports a screenshot, the attachments and optionally a rendered page of the forum topics and their posts
+type ForumTopicsSection struct{}
+
+func (ForumTopicsSection) Name() string {
+	return forumTopicsDir
+}
+
+func (ForumTopicsSection) Export(ctx context.Context, h *SectionHandle, outDir string) error {
+	contract := h.Contract()
+	if err := utils.MkDir(utils.JoinFilePath(outDir, forumTopicsDir)); err != nil {
+		return err
+	}
+
+	forumTopics := h.API().IterForumTopics(contract.PlaceID)
+	for forumTopics.Next(ctx) {
+		forumTopic := forumTopics.Item()
+		logrus.Infof("----------\nForum topic %d:%s", forumTopic.ID, forumTopic.DisplayName)
+
+		forumTopicDir := utils.JoinFilePath(outDir, forumTopicsDir, forumTopic.FileBaseName())
+		if err := utils.MkDir(forumTopicDir); err != nil {
+			return err
+		}
+
+		if err := h.API().CreateForumTopicScreenshot(ctx, forumTopic, forumTopicDir); err != nil && !errors.Is(err, eseis.ErrChromeDisabled) {
+			err = fmt.Errorf("failed to create forum topic screenshot forumTopic=%d: %w", forumTopic.ID, err)
+			if err = h.ItemFailed(ctx, strconv.Itoa(forumTopic.ID), forumTopic.URL, err); err != nil {
+				return err
+			}
+		}
+
+		if err := h.SubmitAttachments(forumTopic.Attachments, forumTopicDir); err != nil {
+			return err
+		}
+
+		topicPosts, err := h.API().AllTopicPosts(ctx, contract.PlaceID, forumTopic.ID)
+		if err != nil {
+			err = fmt.Errorf("failed to get topic posts for placeID=%d forumTopic=%d: %w", contract.PlaceID, forumTopic.ID, err)
+			if err = h.ItemFailed(ctx, strconv.Itoa(forumTopic.ID), forumTopic.URL, err); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, post := range topicPosts {
+			if err := h.SubmitAttachments(post.Attachments, forumTopicDir); err != nil {
+				return err
+			}
+		}
+
+		if err := h.RenderForumTopic(forumTopic, topicPosts, forumTopicDir); err != nil {
+			if err = h.ItemFailed(ctx, strconv.Itoa(forumTopic.ID), forumTopic.URL, err); err != nil {
+				return err
+			}
+		}
+	}
+	if err := forumTopics.Err(); err != nil {
+		return fmt.Errorf("failed to get forum topics for placeId=%d: %w", contract.PlaceID, err)
+	}
+	return nil
+}
+
+// BudgetsSection exports the budgets of each fiscal year with their account entries and documents
+type BudgetsSection struct{}
+
+func (BudgetsSection) Name() string {
+	return budgetsDir
+}
+
+func (BudgetsSection) Export(ctx context.Context, h *SectionHandle, outDir string) error {
+	contract := h.Contract()
+	if err := utils.MkDir(utils.JoinFilePath(outDir, budgetsDir)); err != nil {
+		return err
+	}
+
+	fiscalYears, err := h.API().GetFiscalYears(ctx, contract.PlaceID)
+	if err != nil {
+		return fmt.Errorf("failed to get fiscal years for placeId=%d: %w", contract.PlaceID, err)
+	}
+
+	for _, fiscalYear := range fiscalYears {
+		budgets, err := h.API().GetBudgets(ctx, contract.PlaceID, fiscalYear.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get budgets for placeId=%d and fiscalYear=%d: %w", contract.PlaceID, fiscalYear.ID, err)
+		}
+		fiscalYearDirName := utils.JoinFilePath(
+			outDir,
+			budgetsDir,
+			strings.ReplaceAll(fiscalYear.DisplayName, "/", "_"),
+		)
+		if err = utils.MkDir(fiscalYearDirName); err != nil {
+			return err
+		}
+		if err = h.ExportInfoFile(fiscalYear, utils.JoinFilePath(fiscalYearDirName, "info.json")); err != nil {
+			return err
+		}
+
+		for _, budget := range budgets {
+			budgetDirName := utils.JoinFilePath(
+				fiscalYearDirName,
+				utils.SanitizePath(budget.DisplayName),
+			)
+			if err = utils.MkDir(budgetDirName); err != nil {
+				return err
+			}
+			if err = h.ExportInfoFile(budget, utils.JoinFilePath(budgetDirName, "info.json")); err != nil {
+				return err
+			}
+
+			accountPlaceEntries, err := h.API().GetAccountPlaceEntries(ctx, budget.ID)
+			if err != nil {
+				err = fmt.Errorf("failed to get account place entries for budgetID=%d: %w", budget.ID, err)
+				if err = h.ItemFailed(ctx, strconv.Itoa(budget.ID), "", err); err != nil {
+					return err
+				}
+				continue
+			}
+			for _, accountPlaceEntry := range accountPlaceEntries {
+				exportDocumentName := fmt.Sprintf(
+					"%s_%d_%s",
+					accountPlaceEntry.OperationDate.Format(time.RFC3339),
+					accountPlaceEntry.Amount,
+					utils.SanitizePath(accountPlaceEntry.DisplayName),
+				)
+				if err = h.ExportInfoFile(newAccountPlaceEntryInfo(accountPlaceEntry), utils.JoinFilePath(budgetDirName, fmt.Sprintf("%s.json", exportDocumentName))); err != nil {
+					if err = h.ItemFailed(ctx, accountPlaceEntry.UUID, "", err); err != nil {
+						return err
+					}
+				}
+				document := eseis.DocumentRef{
+					ID:          accountPlaceEntry.ID,
+					UUID:        accountPlaceEntry.UUID,
+					DisplayName: exportDocumentName,
+					UpdatedAt:   accountPlaceEntry.UpdatedAt,
+				}
+				if err = h.SubmitDocument(document, budgetDirName); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}